@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appendCSVRow appends one result row to a CSV log at path, writing the
+// header first when the file is new or empty.
+func appendCSVRow(path string, output ResultOutput, format outputFormat) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open append CSV: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat append CSV: %v", err)
+	}
+
+	writer := newCSVWriter(file, format)
+	if info.Size() == 0 {
+		if err := writer.Write(format.selectColumns(csvHeaders)); err != nil {
+			return fmt.Errorf("failed to write CSV headers: %v", err)
+		}
+	}
+	if err := writer.Write(format.selectColumns(csvRow(output, format.SigFigs))); err != nil {
+		return fmt.Errorf("failed to write CSV data: %v", err)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV data: %v", err)
+	}
+	return nil
+}
+
+// parseSince parses a trailing window such as "30d", "2w" or any Go
+// duration like "36h".
+func parseSince(s string) (time.Duration, error) {
+	day := 24 * time.Hour
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": 7 * day} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// TrailingSummary aggregates the append CSV rows inside a time window.
+type TrailingSummary struct {
+	Since            time.Time
+	Runs             int
+	Skipped          int
+	AnnualCostSaved  float64 // sum of $/year across runs
+	ElectricitySaved float64 // sum of kWh/day across runs
+}
+
+// summarizeSince reads an append CSV written with the delimiter comma and
+// totals the rows whose Timestamp falls within window of now. Malformed
+// rows are skipped with a warning.
+func summarizeSince(r io.Reader, comma rune, window time.Duration, now time.Time, warn io.Writer) (TrailingSummary, error) {
+	summary := TrailingSummary{Since: now.Add(-window)}
+
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return summary, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	timeCol, ok1 := columns["Timestamp"]
+	costCol, ok2 := columns["Annual Cost Saved ($)"]
+	if !ok2 {
+		// Files appended before the header was corrected held the same
+		// annual figure under a daily label.
+		costCol, ok2 = columns["Daily Cost Saved ($)"]
+	}
+	kwhCol, ok3 := columns["Electricity Saved (kWh/day)"]
+	if !ok1 || !ok2 || !ok3 {
+		return summary, fmt.Errorf("CSV is missing the Timestamp, Electricity Saved or Cost Saved columns")
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(warn, "Warning: skipping line %d: %v\n", line, err)
+			summary.Skipped++
+			continue
+		}
+		if len(record) != len(header) {
+			fmt.Fprintf(warn, "Warning: skipping line %d: expected %d fields, got %d\n",
+				line, len(header), len(record))
+			summary.Skipped++
+			continue
+		}
+
+		ts, err := time.Parse(time.RFC3339, strings.TrimSpace(record[timeCol]))
+		if err != nil {
+			fmt.Fprintf(warn, "Warning: skipping line %d: invalid timestamp %q\n", line, record[timeCol])
+			summary.Skipped++
+			continue
+		}
+		cost, err1 := strconv.ParseFloat(record[costCol], 64)
+		kwh, err2 := strconv.ParseFloat(record[kwhCol], 64)
+		if err1 != nil || err2 != nil {
+			fmt.Fprintf(warn, "Warning: skipping line %d: invalid number\n", line)
+			summary.Skipped++
+			continue
+		}
+
+		if ts.Before(summary.Since) || ts.After(now) {
+			continue
+		}
+		summary.Runs++
+		summary.AnnualCostSaved += cost
+		summary.ElectricitySaved += kwh
+	}
+
+	return summary, nil
+}
+
+// printTrailingSummary writes the aggregates for a report-since run.
+func printTrailingSummary(w io.Writer, s TrailingSummary) {
+	fmt.Fprintf(w, "Runs logged since %s: %d\n", s.Since.Format(time.RFC3339), s.Runs)
+	if s.Skipped > 0 {
+		fmt.Fprintf(w, "Malformed rows skipped: %d\n", s.Skipped)
+	}
+	if s.Runs == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Total electricity saved: %.2f kWh/day\n", s.ElectricitySaved)
+	fmt.Fprintf(w, "Total annual cost savings: %.2f $/year\n", s.AnnualCostSaved)
+	fmt.Fprintf(w, "Mean annual cost savings per run: %.2f $/year\n", s.AnnualCostSaved/float64(s.Runs))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// auditEntry is one line of the --audit-log: everything needed to show
+// what was calculated, from what, and when.
+type auditEntry struct {
+	Timestamp string       `json:"timestamp"`
+	Command   string       `json:"command"`
+	InputHash string       `json:"input_hash"`
+	Config    Config       `json:"config"`
+	Result    ResultOutput `json:"result"`
+}
+
+// auditLog appends an entry for every completed calculation. The file is
+// only ever opened with O_APPEND, so earlier lines are never rewritten.
+// A nil *auditLog records nothing.
+type auditLog struct {
+	path    string
+	command string
+	mu      sync.Mutex
+}
+
+// audit is the log every calculation is recorded to; nil unless
+// --audit-log was given.
+var audit *auditLog
+
+// bindAuditFlag registers --audit-log on fs.
+func bindAuditFlag(fs *pflag.FlagSet, path *string) {
+	fs.StringVar(path, "audit-log", "",
+		"Append every calculation, with its full inputs and result, as one JSON line to this file")
+}
+
+// openAuditLog sets audit to append to path for command, or leaves it
+// nil when path is empty.
+func openAuditLog(path, command string) {
+	if path != "" {
+		audit = &auditLog{path: path, command: command}
+	}
+}
+
+// record appends one entry for result computed from config.
+func (l *auditLog) record(result Result, config Config) error {
+	if l == nil {
+		return nil
+	}
+	output := newResultOutput(result, config)
+	line, err := canonicalJSON(auditEntry{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Command:   l.command,
+		InputHash: output.InputHash,
+		Config:    config,
+		Result:    output,
+	}, "")
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// ieccSHGC is the IECC 2021 prescriptive maximum SHGC for commercial
+// fixed fenestration by climate zone number.
+var ieccSHGC = map[byte]float64{
+	'0': 0.25, '1': 0.25, '2': 0.25, '3': 0.25,
+	'4': 0.36, '5': 0.38, '6': 0.38, '7': 0.40, '8': 0.40,
+}
+
+// defaultCodeSHGC is used when the location's climate zone is unknown;
+// it is the IECC limit for the cooling-dominated zones 0-3.
+const defaultCodeSHGC = 0.25
+
+// CodeBaselineResult compares the proposed glazing with meeting the
+// energy code minimum for the same existing windows.
+type CodeBaselineResult struct {
+	SHGC                   float64 `json:"shgc"`
+	Source                 string  `json:"source"`
+	AnnualCostSaved        float64 `json:"code_annual_cost_saved_usd"`
+	IncrementalCostSaved   float64 `json:"incremental_annual_cost_saved_usd"`
+	IncrementalCO2Avoided  float64 `json:"incremental_co2_avoided_kg_year"`
+	ProposedBetterThanCode bool    `json:"proposed_better_than_code"`
+}
+
+// resolveCodeSHGC returns the code-minimum SHGC for config and its
+// source: the --code-shgc override, Title 24 in California, or the IECC
+// limit for the location's climate zone. The bundled limits are those
+// for nonresidential buildings such as the modelled clinic.
+func resolveCodeSHGC(config Config) (float64, string) {
+	if config.CodeSHGC > 0 {
+		return config.CodeSHGC, "input"
+	}
+	data, ok := lookupLocation(config.Location)
+	if ok && data.State == "CA" {
+		return 0.25, "CA Title 24 2022 nonresidential"
+	}
+	if ok && data.ClimateZone != "" {
+		if shgc, ok := ieccSHGC[data.ClimateZone[0]]; ok {
+			return shgc, fmt.Sprintf("IECC 2021 commercial, climate zone %s", data.ClimateZone)
+		}
+	}
+	return defaultCodeSHGC, "IECC 2021 commercial, climate zones 0-3"
+}
+
+// codeBaseline prices the retrofit a code-minimum window would achieve
+// over the same existing glazing and how much more the proposed one
+// saves. A code limit above the existing SHGC would not require any
+// change, so the baseline is then no retrofit at all.
+func codeBaseline(result Result, config Config) *CodeBaselineResult {
+	shgc, source := resolveCodeSHGC(config)
+
+	baseline := config
+	baseline.SHGCProposed = min(shgc, config.SHGCExisting)
+	incident := config.SolarReduction / retrofitFraction(config)
+	baseline.SolarReduction = incident * retrofitFraction(baseline)
+	code := modelOf(baseline).Compute(baseline)
+	code.AnnualCostSaved *= coolingSeason(baseline)
+	// The roof is the same either way, so it cancels out of the increment.
+	code.Roof = result.Roof
+	applyEmissions(&code, baseline)
+
+	return &CodeBaselineResult{
+		SHGC:                   shgc,
+		Source:                 source,
+		AnnualCostSaved:        code.AnnualCostSaved,
+		IncrementalCostSaved:   result.AnnualCostSaved - code.AnnualCostSaved,
+		IncrementalCO2Avoided:  result.AnnualCO2Avoided - code.AnnualCO2Avoided,
+		ProposedBetterThanCode: config.SHGCProposed < shgc,
+	}
+}
+
+// codeBaselineSummary is the headline comparison printed with the result.
+func codeBaselineSummary(b *CodeBaselineResult) string {
+	verb := "more"
+	saved, co2 := b.IncrementalCostSaved, b.IncrementalCO2Avoided
+	if saved < 0 {
+		verb, saved, co2 = "less", -saved, -co2
+	}
+	return fmt.Sprintf("You save $%.2f/year %s than code minimum (SHGC %.2f, %s) and avoid %.1f kg CO2/year %s",
+		saved, verb, b.SHGC, b.Source, co2, verb)
+}
+
+// BaselineDelta compares the scenario with the reference scenario of a
+// --baseline-config file; each difference is the scenario less the
+// reference.
+type BaselineDelta struct {
+	Path                   string  `json:"path"`
+	BaselineNetSavings     float64 `json:"baseline_net_annual_savings_usd"`
+	NetSavings             float64 `json:"net_annual_savings_delta_usd"`
+	ElectricitySaved       float64 `json:"electricity_saved_delta_kwh_day"`
+	PeakElectricityReduced float64 `json:"peak_electricity_reduced_delta_kw"`
+	AnnualCO2Avoided       float64 `json:"co2_avoided_delta_kg_year"`
+}
+
+// loadBaselineConfig reads a reference scenario from a config file over
+// the defaults, the way --config would, and calculates it.
+func loadBaselineConfig(path string) (Result, error) {
+	baseline := DefaultConfig()
+	if _, err := loadConfigFile(path, &baseline); err != nil {
+		return Result{}, err
+	}
+	result, _, err := runScenario(baseline, map[string]string{}, io.Discard)
+	if err != nil {
+		return Result{}, fmt.Errorf("baseline config %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// baselineDelta compares result with the reference result from path.
+func baselineDelta(path string, result, baseline Result) *BaselineDelta {
+	return &BaselineDelta{
+		Path:                   path,
+		BaselineNetSavings:     baseline.NetAnnualSavings,
+		NetSavings:             result.NetAnnualSavings - baseline.NetAnnualSavings,
+		ElectricitySaved:       result.ElectricitySaved - baseline.ElectricitySaved,
+		PeakElectricityReduced: result.PeakElectricityReduced - baseline.PeakElectricityReduced,
+		AnnualCO2Avoided:       result.AnnualCO2Avoided - baseline.AnnualCO2Avoided,
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// exitInterrupted is batch's exit code after an interrupt, 128 + SIGINT
+// as a shell reports it.
+const exitInterrupted = 130
+
+// runBatchCommand is the batch command: every row of a scenarios CSV is
+// calculated over the base configuration and the results are streamed to
+// one JSON array and CSV, so no more than a row is held at a time. An
+// interrupt stops it before the next row and writes the results so far;
+// a second interrupt kills it.
+func runBatchCommand(args []string) int {
+	config := DefaultConfig()
+	var ndjson string
+	var failOnWarn bool
+	var maxPayback float64
+	var auditPath string
+	var positiveNet bool
+	var withSummary bool
+	var dedupeBy []string
+	var dedupe string
+	var diversity float64
+
+	fs := pflag.NewFlagSet("batch", pflag.ExitOnError)
+	flags := bindConfigFlags(fs, &config)
+	bindOutputFlags(fs, &config)
+	fs.StringVar(&ndjson, "ndjson", "",
+		"Also append every scenario as one JSON line to this file")
+	bindAuditFlag(fs, &auditPath)
+	fs.StringSliceVar(&dedupeBy, "dedupe-by", nil,
+		"Columns identifying a scenario, e.g. location; rows repeating them are handled per --dedupe")
+	fs.StringVar(&dedupe, "dedupe", "error",
+		"What to do with duplicate rows: error, first, last or average (numeric cells are averaged)")
+	fs.BoolVar(&withSummary, "summary", false,
+		"Print the mean, median and p90 of the savings, CO2 and payback in bounded memory")
+	fs.Float64Var(&diversity, "diversity-factor", 0,
+		"Report the portfolio's peak reduction as the sum over the buildings divided by this factor (at least 1)")
+	fs.Float64Var(&maxPayback, "max-payback", 0,
+		"Keep only scenarios whose simple payback is at most this many years (needs project_cost)")
+	fs.BoolVar(&positiveNet, "require-positive-net", false,
+		"Treat scenarios whose heating penalty makes the net savings negative as failed rows")
+	fs.BoolVar(&failOnWarn, "fail-on-warning", false,
+		"Exit non-zero if any warning was printed, after writing the results")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  calculator batch [flags] scenarios.csv\n\n")
+		fmt.Fprintf(os.Stderr, "Each CSV column is a config file key such as solar_reduction or shgc.\n")
+		fmt.Fprintf(os.Stderr, "Non-empty cells override the base configuration from --config and flags.\n")
+		fmt.Fprintf(os.Stderr, "Ctrl-C stops before the next row, writes the results so far and exits %d.\n\n", exitInterrupted)
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	flags.record()
+	fileKeys, err := flags.load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	openAuditLog(auditPath, "batch")
+	colorWarnings(config.Color)
+
+	if diversity != 0 && diversity < 1 {
+		fmt.Println("Error: Diversity factor must be at least 1")
+		return 1
+	}
+
+	if err := checkOutputDir(config.OutputDir); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	format, err := outputFormatOf(config)
+	if err != nil {
+		file.Close()
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	stream, err := newResultStream(config.OutputDir, "solar_cooling_batch_"+fileTimestamp(format.Now()), format)
+	if err != nil {
+		file.Close()
+		fmt.Printf("Error saving results: %v\n", err)
+		return 1
+	}
+
+	options := batchOptions{PositiveNet: positiveNet, DedupeBy: dedupeBy, Dedupe: dedupe, Context: ctx}
+	if withSummary {
+		options.Summary = newBatchSummary()
+	}
+	peak := portfolioPeak{DiversityFactor: diversity}
+	var written, filtered int
+	var writeErr error
+	emit := func(output ResultOutput) error {
+		if maxPayback > 0 && !paysBackWithin(output, maxPayback) {
+			filtered++
+			return nil
+		}
+		if writeErr = stream.add(output); writeErr == nil && ndjson != "" {
+			writeErr = appendNDJSON(ndjson, output)
+		}
+		if writeErr != nil {
+			return writeErr
+		}
+		peak.add(output)
+		written++
+		return nil
+	}
+	rowErr := runBatch(file, config, flags.sources(fileKeys), options, emit)
+	file.Close()
+	if writeErr != nil {
+		stream.discard()
+		fmt.Printf("Error saving results: %v\n", writeErr)
+		return 1
+	}
+
+	status := 0
+	if rowErr != nil {
+		printErrors(rowErr)
+		status = 1
+	}
+	if ctx.Err() != nil {
+		status = exitInterrupted
+	}
+	if maxPayback > 0 {
+		fmt.Printf("%d of %d scenarios filtered out by --max-payback %g\n",
+			filtered, written+filtered, maxPayback)
+	}
+	if written == 0 {
+		stream.discard()
+		return max(status, 1)
+	}
+
+	jsonPath, csvPath, err := stream.commit()
+	if err != nil {
+		fmt.Printf("Error saving results: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%d scenarios written to %s and %s\n", written, jsonPath, csvPath)
+	if options.Summary != nil {
+		printBatchSummary(os.Stdout, options.Summary)
+	}
+	if diversity > 0 {
+		printPortfolioPeak(os.Stdout, peak.diversified())
+	}
+	return failOnWarnings(failOnWarn, status)
+}
+
+// batchOptions are the batch command's settings beyond the scenarios.
+type batchOptions struct {
+	// PositiveNet fails rows whose net savings are negative.
+	PositiveNet bool
+	// Summary, when non-nil, is given every result.
+	Summary *batchSummary
+	// DedupeBy names the columns that identify a scenario; rows that
+	// repeat them are resolved per Dedupe, one of dedupeModes.
+	DedupeBy []string
+	Dedupe   string
+	// Context, when done, stops the batch before the next row.
+	Context context.Context
+}
+
+// batchRow is one data row of a scenarios CSV and its line number.
+type batchRow struct {
+	Line   int
+	Record []string
+}
+
+// runBatch computes one scenario per CSV row read from r and passes each
+// result to emit as it is computed. The header names Config keys as used
+// in a config file, and each row's non-empty cells override base. Rows
+// that fail are skipped and their errors returned joined, each prefixed
+// with the row's line number. An error from emit stops the batch and is
+// returned as is.
+func runBatch(r io.Reader, base Config, sources map[string]string, options batchOptions,
+	emit func(ResultOutput) error) error {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	for i, key := range header {
+		header[i] = strings.TrimSpace(key)
+		if _, err := configField(&base, header[i]); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	interrupted := func() bool { return options.Context != nil && options.Context.Err() != nil }
+	if len(options.DedupeBy) == 0 {
+		for line := 2; ; line++ {
+			if interrupted() {
+				fmt.Printf("Interrupted: %d rows completed\n", line-2)
+				break
+			}
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %v", line, err))
+				continue
+			}
+			if err := emitBatchRow(batchRow{line, record}, header, base, sources, options, &errs, emit); err != nil {
+				return err
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	// Duplicates can be anywhere in the file, so deduplicating holds every
+	// row's cells, though still not the results.
+	var rows []batchRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", line, err))
+			continue
+		}
+		rows = append(rows, batchRow{line, record})
+	}
+	if rows, err = dedupeRows(header, rows, options.DedupeBy, options.Dedupe, os.Stdout); err != nil {
+		return err
+	}
+	for n, row := range rows {
+		if interrupted() {
+			fmt.Printf("Interrupted: %d of %d rows completed\n", n, len(rows))
+			break
+		}
+		if err := emitBatchRow(row, header, base, sources, options, &errs, emit); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// emitBatchRow computes row and passes its output to the summary and to
+// emit, or adds its error to errs. Only an error from emit is returned.
+func emitBatchRow(row batchRow, header []string, base Config, sources map[string]string, options batchOptions,
+	errs *[]error, emit func(ResultOutput) error) error {
+	output, err := runBatchRow(row, header, base, sources, options)
+	if err != nil {
+		*errs = append(*errs, err)
+		return nil
+	}
+	options.Summary.add(output)
+	return emit(output)
+}
+
+// runBatchRow computes one row over base, returning its output or the
+// row's error prefixed with its line number.
+func runBatchRow(row batchRow, header []string, base Config, sources map[string]string,
+	options batchOptions) (ResultOutput, error) {
+	scenario := base
+	rowSources := make(map[string]string, len(sources))
+	for key, source := range sources {
+		rowSources[key] = source
+	}
+	var err error
+	for i, cell := range row.Record {
+		cell = strings.TrimSpace(cell)
+		if cell == "" {
+			continue
+		}
+		if err = setConfigValue(&scenario, header[i], cell); err != nil {
+			break
+		}
+		rowSources[header[i]] = "batch"
+	}
+	if err == nil && scenario.Strict {
+		if missing := unaffirmedAssumptions(scenario, rowSources); len(missing) > 0 {
+			err = fmt.Errorf("--strict requires explicit values for: --%s", strings.Join(missing, ", --"))
+		}
+	}
+	var result Result
+	if err == nil {
+		result, scenario, err = runScenario(scenario, rowSources, io.Discard)
+	}
+	if err == nil && options.PositiveNet {
+		err = checkPositiveNet(result)
+	}
+	if err != nil {
+		return ResultOutput{}, fmt.Errorf("line %d: %s", row.Line, strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	return newResultOutput(result, scenario), nil
+}
+
+// paysBackWithin reports whether output pays back within maxYears.
+// Scenarios without a project cost, or that never pay back, do not.
+func paysBackWithin(output ResultOutput, maxYears float64) bool {
+	return output.PaybackYears != nil && *output.PaybackYears <= maxYears
+}
+
+// configField returns the scalar Config field with the given JSON key.
+func configField(config *Config, key string) (reflect.Value, error) {
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != key || name == "-" {
+			continue
+		}
+		switch v.Field(i).Kind() {
+		case reflect.String, reflect.Float64, reflect.Int, reflect.Bool:
+			return v.Field(i), nil
+		}
+		return reflect.Value{}, fmt.Errorf("%s can only be set in a config file", key)
+	}
+	return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+}
+
+// setConfigValue parses text into the Config field with the given key.
+func setConfigValue(config *Config, key, text string) error {
+	field, err := configField(config, key)
+	if err != nil {
+		return err
+	}
+	switch field.Kind() {
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %q", key, text)
+		}
+		field.SetFloat(value)
+	case reflect.Int:
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", key, text)
+		}
+		field.SetInt(int64(value))
+	case reflect.Bool:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("%s: invalid boolean %q", key, text)
+		}
+		field.SetBool(value)
+	default:
+		field.SetString(text)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// bundleFile is one file of a --bundle archive.
+type bundleFile struct {
+	Name        string
+	Description string
+	Data        []byte
+}
+
+// bundleFileFrom reads the output file at path into the bundle under its
+// base name.
+func bundleFileFrom(path, description string) (bundleFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return bundleFile{}, fmt.Errorf("failed to bundle %s: %v", path, err)
+	}
+	return bundleFile{filepath.Base(path), description, data}, nil
+}
+
+// bundleFiles gathers calc's artifacts for --bundle: the result files
+// already written, the report as printed (or as the template formats it)
+// and the manifest, written by --manifest or else rendered as Markdown.
+func bundleFiles(result Result, config Config, sources map[string]string,
+	jsonPath, csvPath, manifest, tmplPath string, tmpl *template.Template) ([]bundleFile, error) {
+	var files []bundleFile
+	for _, f := range [][2]string{{jsonPath, "result JSON"}, {csvPath, "result CSV"}} {
+		file, err := bundleFileFrom(f[0], f[1])
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	var report bytes.Buffer
+	if tmpl != nil {
+		if err := tmpl.Execute(&report, newResultOutput(result, config)); err != nil {
+			return nil, err
+		}
+		files = append(files, bundleFile{strings.TrimSuffix(filepath.Base(tmplPath), ".tmpl"),
+			"report from " + filepath.Base(tmplPath), report.Bytes()})
+	} else {
+		plain := config
+		plain.Color = "never"
+		printResult(&report, result, plain, true)
+		files = append(files, bundleFile{"report.txt", "verbose text report", report.Bytes()})
+	}
+
+	if manifest != "" {
+		file, err := bundleFileFrom(manifest, "assumptions manifest")
+		if err != nil {
+			return nil, err
+		}
+		return append(files, file), nil
+	}
+	data, err := manifestData("manifest.md", assumptionsManifest(config, sources, result))
+	if err != nil {
+		return nil, err
+	}
+	return append(files, bundleFile{"manifest.md", "assumptions manifest", data}), nil
+}
+
+// bundlePath places a relative --bundle path in the output directory.
+func bundlePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// writeBundle writes files to the zip archive at path, preceded by an
+// index.txt listing them. Every entry is stamped at, so a fixed
+// --output-timestamp gives the same archive. Nothing appears at path
+// unless the whole archive is written.
+func writeBundle(path string, files []bundleFile, reproduce string, at time.Time) error {
+	var index bytes.Buffer
+	fmt.Fprintf(&index, "Solar Cooling Energy Calculator v%s, %s\n", version, at.Format(time.RFC3339))
+	fmt.Fprintf(&index, "Reproduce with: %s\n\n", reproduce)
+	for _, f := range files {
+		fmt.Fprintf(&index, "%-44s %9d  %s\n", f.Name, len(f.Data), f.Description)
+	}
+	files = append([]bundleFile{{"index.txt", "this list", index.Bytes()}}, files...)
+
+	file, err := createOutputFile(path, false)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %v", err)
+	}
+	archive := zip.NewWriter(file)
+	for _, f := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: at})
+		if err == nil {
+			_, err = entry.Write(f.Data)
+		}
+		if err != nil {
+			file.discard()
+			return fmt.Errorf("failed to write bundle: %v", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		file.discard()
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.tmp)
+		return fmt.Errorf("failed to write bundle: %v", err)
+	}
+	return commitOutputFiles(file)
+}
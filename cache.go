@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachePath returns the location of a named entry in the per-user cache.
+func cachePath(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "solar-calc", name), nil
+}
+
+// readCache decodes a cached entry into v, reporting whether it was found
+// and how old it is.
+func readCache(name string, v any) (time.Duration, bool) {
+	path, err := cachePath(name)
+	if err != nil {
+		return 0, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	// A zero age means live data to the callers, so even a fresh entry
+	// is at least a nanosecond old.
+	age := max(time.Since(info.ModTime()), time.Nanosecond)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	return age, json.Unmarshal(data, v) == nil
+}
+
+// cacheFresh reports whether an entry of age is within ttl; a ttl of 0
+// never expires one.
+func cacheFresh(age, ttl time.Duration) bool {
+	return ttl <= 0 || age <= ttl
+}
+
+// cacheAge describes how old a cache entry is, to the hour or the day.
+func cacheAge(age time.Duration) string {
+	switch {
+	case age < time.Hour:
+		return "under an hour old"
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%d hours old", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%d days old", int(age.Hours()/24))
+	}
+}
+
+// writeCache stores v under name. Caching is best effort, so failures are
+// ignored and the next run simply fetches again.
+func writeCache(name string, v any) {
+	path, err := cachePath(name)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheFresh(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age, ttl time.Duration
+		fresh    bool
+	}{
+		{time.Hour, 30 * day, true},
+		{30 * day, 30 * day, true},
+		{31 * day, 30 * day, false},
+		{400 * day, 0, true}, // 0 never expires
+		{time.Nanosecond, time.Nanosecond, true},
+	}
+	for _, tt := range tests {
+		if got := cacheFresh(tt.age, tt.ttl); got != tt.fresh {
+			t.Errorf("cacheFresh(%v, %v) = %v, want %v", tt.age, tt.ttl, got, tt.fresh)
+		}
+	}
+}
+
+func TestCacheAge(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{time.Minute, "under an hour old"},
+		{5 * time.Hour, "5 hours old"},
+		{47 * time.Hour, "47 hours old"},
+		{72 * time.Hour, "3 days old"},
+	}
+	for _, tt := range tests {
+		if got := cacheAge(tt.age); got != tt.want {
+			t.Errorf("cacheAge(%v) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+const cachedGHI, liveGHI = 4.2, 5.6
+
+// fakePVWatts points pvwattsURL at a server answering liveGHI, or failing
+// when offline, and gives the test an empty cache.
+func fakePVWatts(t *testing.T, online bool) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !online {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"outputs": {"solrad_annual": %g, "solrad_monthly": [%s]}}`,
+			liveGHI, strings.TrimSuffix(strings.Repeat(fmt.Sprint(liveGHI, ","), 12), ","))
+	}))
+	t.Cleanup(server.Close)
+	url := pvwattsURL
+	t.Cleanup(func() { pvwattsURL = url })
+	pvwattsURL = server.URL
+}
+
+// cacheSolar stores cachedGHI under name, last written age ago.
+func cacheSolar(t *testing.T, name string, age time.Duration) {
+	writeCache(name, SolarResource{AnnualGHI: cachedGHI, Source: "NREL PVWatts"})
+	path, err := cachePath(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSolarCacheTTL(t *testing.T) {
+	const cached, live = cachedGHI, liveGHI
+	day := 24 * time.Hour
+	tests := []struct {
+		name     string
+		entryAge time.Duration // 0 for no entry
+		ttl      time.Duration
+		online   bool
+		ghi      float64
+		fromAge  bool   // served from the cache, with its age
+		warning  string // "" for none
+		fails    bool
+	}{
+		{"fresh entry", day, 30 * day, false, cached, true, "", false},
+		{"expired entry is refetched", 40 * day, 30 * day, true, live, false, "", false},
+		{"expired entry offline", 40 * day, 30 * day, false, cached, true, "40 days old and past --cache-ttl", false},
+		{"ttl 0 never expires", 400 * day, 0, false, cached, true, "", false},
+		{"no entry", 0, 30 * day, true, live, false, "", false},
+		{"no entry offline", 0, 30 * day, false, 0, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakePVWatts(t, tt.online)
+			const lat, lng = 38.58, -121.49
+			name := fmt.Sprintf("nrel_%.2f_%.2f.json", lat, lng)
+			if tt.entryAge > 0 {
+				cacheSolar(t, name, tt.entryAge)
+			}
+
+			resource, warning, err := fetchSolarResource("key", lat, lng, tt.ttl)
+			if (err != nil) != tt.fails {
+				t.Fatalf("err = %v, want failure: %v", err, tt.fails)
+			}
+			if resource.AnnualGHI != tt.ghi {
+				t.Errorf("AnnualGHI = %g, want %g", resource.AnnualGHI, tt.ghi)
+			}
+			if fromAge := resource.CacheAge > 0; fromAge != tt.fromAge {
+				t.Errorf("CacheAge = %v, want one: %v", resource.CacheAge, tt.fromAge)
+			}
+			if (warning == "") != (tt.warning == "") || !strings.Contains(warning, tt.warning) {
+				t.Errorf("warning %q, want %q", warning, tt.warning)
+			}
+			if tt.online {
+				var stored SolarResource
+				if _, ok := readCache(name, &stored); !ok || stored.AnnualGHI != live {
+					t.Errorf("cache holds %g after a fetch, want %g", stored.AnnualGHI, live)
+				}
+			}
+		})
+	}
+}
+
+func TestSolarLookupReportsTheCacheAsOutput(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name    string
+		age     time.Duration
+		online  bool
+		note    string // in the output, "" for none
+		warning string // in the warnings, "" for none
+	}{
+		{"fresh entry", 2 * day, false, "Note: NREL PVWatts irradiance read from cache, 2 days old (--cache-ttl 30d)", ""},
+		{"refetched", 40 * day, true, "", ""},
+		{"expired entry offline", 40 * day, false, "", "; using the cached irradiance, 40 days old and past --cache-ttl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakePVWatts(t, tt.online)
+			config := defaultConfigWith(func(c *Config) { c.NRELAPIKey, c.Latitude, c.Longitude = "key", 38.58, -121.49 })
+			cacheSolar(t, fmt.Sprintf("nrel_%.2f_%.2f.json", config.Latitude, config.Longitude), tt.age)
+
+			var warn, out strings.Builder
+			if _, err := newSolarLookup(&config, &warn, &out)(); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range []struct{ stream, got, want string }{
+				{"output", out.String(), tt.note},
+				{"warnings", warn.String(), tt.warning},
+			} {
+				if (c.got == "") != (c.want == "") || !strings.Contains(c.got, c.want) {
+					t.Errorf("%s %q, want %q", c.stream, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveFallsBackToBundledDataWithAWarning(t *testing.T) {
+	tests := []struct {
+		name    string
+		online  bool
+		cached  bool
+		source  string
+		warning string // "" for none
+	}{
+		{"online", true, false, "NREL PVWatts", ""},
+		{"offline, no cache", false, false, "bundled", "using bundled irradiance for Sacramento"},
+		{"offline, expired cache", false, true, "NREL PVWatts", "using the cached irradiance"},
+	}
+	for _, tt := range tests {
+		t.Run("irradiance "+tt.name, func(t *testing.T) {
+			fakePVWatts(t, tt.online)
+			config := defaultConfigWith(func(c *Config) {
+				c.NRELAPIKey, c.Latitude, c.Longitude, c.CacheTTL = "key", 38.58, -121.49, "1d"
+			})
+			if tt.cached {
+				cacheSolar(t, fmt.Sprintf("nrel_%.2f_%.2f.json", config.Latitude, config.Longitude), 40*24*time.Hour)
+			}
+			resource, warning, err := resolveSolarResource(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resource.Source != tt.source {
+				t.Errorf("source %q, want %q", resource.Source, tt.source)
+			}
+			if (warning == "") != (tt.warning == "") || !strings.Contains(warning, tt.warning) {
+				t.Errorf("warning %q, want %q", warning, tt.warning)
+			}
+		})
+	}
+
+	t.Run("price offline, no cache", func(t *testing.T) {
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		defer func(url string) { eiaURL = url }(eiaURL)
+		eiaURL = server.URL
+
+		estimate, warning, err := resolveStatePrice("key", "ca", 24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if estimate.Source != "bundled" || estimate.Price != statePrices["CA"] {
+			t.Errorf("price %g from %q, want the bundled %g", estimate.Price, estimate.Source, statePrices["CA"])
+		}
+		if !strings.Contains(warning, "using bundled price for CA") {
+			t.Errorf("warning %q, want the bundled fallback", warning)
+		}
+	})
+}
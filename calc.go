@@ -0,0 +1,764 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// runCalc is the calc command: one building, printed and saved.
+func runCalc(args []string) (status int) {
+	config := DefaultConfig()
+
+	var (
+		failOnWarn  bool
+		verbose     bool
+		showVersion bool
+		interactive bool
+		dumpConfig  bool
+		merge       []string
+		watch       bool
+		validate    bool
+		appendCSV   string
+		reportSince string
+		ndjson      string
+		sensitivity bool
+		compareCOP  []float64
+		compareLocs []string
+		compareWWR  []float64
+		compareFilm []string
+		listFilms   bool
+		tableRows   string
+		tableCols   string
+		auditPath   string
+		positiveNet bool
+		baseline    string
+		sanity      bool
+		tmplPath    string
+		billsPath   string
+		target      float64
+		manifest    string
+		solveFor    string
+		headline    bool
+		bundle      string
+		monteCarloN int
+		shadowPrice string
+		metric      string
+	)
+
+	fs := pflag.NewFlagSet("calc", pflag.ExitOnError)
+	flags := bindConfigFlags(fs, &config)
+	bindOutputFlags(fs, &config)
+
+	fs.StringVar(&appendCSV, "append-csv", "",
+		"Also append each run as a row to this CSV log")
+	fs.StringVar(&ndjson, "ndjson", "",
+		"Also append each run as one JSON line to this file")
+	bindAuditFlag(fs, &auditPath)
+	fs.StringVar(&reportSince, "report-since", "",
+		"Summarize --append-csv rows logged within this window (e.g. 30d, 2w, 12h) and exit")
+	fs.BoolVar(&watch, "watch", false,
+		"Recompute and reprint whenever a --config file changes")
+	fs.BoolVar(&validate, "validate-only", false,
+		"Validate the configuration and exit without calculating")
+
+	fs.BoolVar(&headline, "headline-only", false,
+		"Print only the --headline-metric as a bare number on one line; no files unless -o is given")
+	fs.StringVar(&metric, "headline-metric", "annual-savings",
+		"Result --headline-only prints: "+strings.Join(headlineNames(), ", "))
+	fs.StringVar(&tmplPath, "template", "",
+		"Go text/template file that formats the result on stdout instead of the report")
+	fs.StringVar(&billsPath, "measured-bills", "",
+		"CSV of month,before_kwh,after_kwh: report the realized savings and cross-check the model, and exit")
+	fs.StringVar(&bundle, "bundle", "",
+		"Also zip the result JSON and CSV, the report and the manifest, with an index, into this file in --output")
+	fs.StringVar(&manifest, "manifest", "",
+		"Write every input with its value, source and reference standard to this file: Markdown for .md, else JSON")
+	fs.Float64Var(&target, "target-savings", 0,
+		"Solve for the --solve-for input that saves this many $/year with the other inputs fixed, and exit")
+	fs.StringVar(&solveFor, "solve-for", "shgc",
+		"Input solved for by --target-savings: shgc or reduction")
+	fs.BoolVar(&sanity, "sanity", false,
+		"Compare the inputs with typical ranges to catch unit mistakes, and exit")
+	fs.StringVar(&baseline, "baseline-config", "",
+		"Config file of a reference scenario to calculate and report the differences against")
+	fs.BoolVar(&positiveNet, "require-positive-net", false,
+		"Exit non-zero when the heating penalty makes the net annual savings negative")
+	fs.BoolVar(&failOnWarn, "fail-on-warning", false,
+		"Exit non-zero if any warning was printed, after completing the run")
+	fs.BoolVarP(&verbose, "verbose", "v", false,
+		"Show detailed assumptions and calculations")
+	fs.BoolVarP(&showVersion, "version", "V", false,
+		"Show program version")
+	fs.BoolVar(&dumpConfig, "print-config", false,
+		"Print the effective configuration as JSON and exit")
+	fs.StringSliceVar(&merge, "merge", nil,
+		"Merge result JSON files (paths or globs) into one JSON array and CSV")
+	fs.IntVar(&monteCarloN, "monte-carlo", 0,
+		"Sample the inputs this many times by their confidence, report the savings and payback percentiles, and exit")
+	fs.StringVar(&shadowPrice, "shadow-price", "",
+		"Tabulate savings at future electricity prices, e.g. 2025=0.15,2030=0.19 or 0.10:0.30:0.05, write it as CSV and exit")
+	fs.BoolVar(&sensitivity, "tornado", false,
+		"Report how annual savings swing with ±10% in each input, write it as CSV and exit")
+	fs.Float64SliceVar(&compareCOP, "compare-cop", nil,
+		"Tabulate savings at each of these COPs (e.g. 3.0,4.0,5.5) and exit")
+	fs.StringSliceVar(&compareFilm, "compare-film", nil,
+		"Comma-separated bundled films to rank by payback with the other inputs fixed, e.g. 3M-NV35,3M-PR70")
+	fs.BoolVar(&listFilms, "list-films", false,
+		"List the bundled window films and exit")
+	fs.Float64SliceVar(&compareWWR, "compare-wwr", nil,
+		"Tabulate cooling load and savings at each window-to-wall ratio over --wall-area (e.g. 0.2,0.4,0.6) and exit")
+	fs.StringSliceVar(&compareLocs, "compare-locations", nil,
+		"Rank savings for the same building at each of these locations, write it as CSV and exit")
+	fs.StringVar(&tableRows, "table-rows", "",
+		"Precompute a savings table: row axis as key=start:stop:step")
+	fs.StringVar(&tableCols, "table-cols", "",
+		"Column axis for the precomputed table, e.g. electricity_cost=0.08:0.30:0.02")
+	fs.BoolVarP(&interactive, "interactive", "i", false,
+		"Prompt for required values that were not given as flags")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Solar Cooling Energy Calculator for Medical Clinics v%s\n\n", version)
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  calculator [calc] [flags]\n")
+		fmt.Fprintf(os.Stderr, "  calculator <command> [flags]\n\n")
+		printCommands(os.Stderr)
+		fmt.Fprintf(os.Stderr, "Required Flags:\n")
+		fmt.Fprintf(os.Stderr, "  -r, --reduction float   Total solar radiation reduction in kWh/day\n")
+		fmt.Fprintf(os.Stderr, "  Amounts may carry their unit, e.g. -r 100kWh or -c 0.15usd; another unit,\n")
+		fmt.Fprintf(os.Stderr, "  such as -r 150W, is rejected rather than converted.\n\n")
+		fmt.Fprintf(os.Stderr, "Optional Flags (with defaults):\n")
+		fmt.Fprintf(os.Stderr, "  -c, --cost float        Electricity cost in $/kWh (default: the commercial\n")
+		fmt.Fprintf(os.Stderr, "                          average of --state, or of a --location given; else required)\n")
+		fmt.Fprintf(os.Stderr, "      --model string      Calculation model: %s (default: %s)\n",
+			strings.Join(modelNames(), ", "), defaultModel)
+		fmt.Fprintf(os.Stderr, "      --cop float         AC Coefficient of Performance (default: %.1f)\n", config.AC_COP)
+		fmt.Fprintf(os.Stderr, "      --u-factor, --delta-t float  U-factor improvement (W/m²K) and temperature difference (K) for conduction\n")
+		fmt.Fprintf(os.Stderr, "      --system-losses float  Fan and pump power over compressor power (default: 0)\n")
+		fmt.Fprintf(os.Stderr, "      --system-type string   dx or chilled-water part-load curve (default: %s)\n", config.SystemType)
+		fmt.Fprintf(os.Stderr, "      --load-fraction float  Part-load ratio the curve is evaluated at (default: %g)\n", config.LoadFraction)
+		fmt.Fprintf(os.Stderr, "      --cop-min, --cop-max float  Plausible COP range; outside is an error (default: %g-%g)\n",
+			config.COPMin, config.COPMax)
+		fmt.Fprintf(os.Stderr, "      --shgc float        Solar Heat Gain Coefficient (default: %.2f)\n", config.SHGC)
+		fmt.Fprintf(os.Stderr, "      --wwr float         Window to Wall Ratio; only used with --wall-area (default: %.2f)\n", config.WWR)
+		fmt.Fprintf(os.Stderr, "      --transmission-factor float   Transmission factor (default: %.2f)\n", config.TransmissionFactor)
+		fmt.Fprintf(os.Stderr, "      --time-lag-factor float       Time lag factor (default: %.2f)\n", config.TimeLagFactor)
+		fmt.Fprintf(os.Stderr, "      --thermal-mass string         light, medium or heavy: lag and peak damping in place\n")
+		fmt.Fprintf(os.Stderr, "                                    of --time-lag-factor (default: off)\n")
+		fmt.Fprintf(os.Stderr, "      --medical-equip-factor float  Medical equipment factor (default: %.2f)\n", config.MedicalEquipFactor)
+		fmt.Fprintf(os.Stderr, "      --equip-schedule list  Equipment factor by period, e.g. 08:00-17:00=1.6\n")
+		fmt.Fprintf(os.Stderr, "      --load-shape-factor float     Peak-to-mean load ratio for peak kW (default: %.2f)\n", config.LoadShapeFactor)
+		fmt.Fprintf(os.Stderr, "      --load-shape path   48 half-hourly load fractions summing to 1; sets the peak\n")
+		fmt.Fprintf(os.Stderr, "      --monthly-cdd path  12 monthly cooling degree days: savings by month\n")
+		fmt.Fprintf(os.Stderr, "      --tou-periods list  TOU rates over the shape, e.g. 16:00-21:00=0.35 (rest: -c)\n")
+		fmt.Fprintf(os.Stderr, "      --co2-unit unit     Show CO2 avoided in kg, tonne or lb (default: kg)\n")
+		fmt.Fprintf(os.Stderr, "      --sig-figs int Round results in the report and CSV to N significant figures\n")
+		fmt.Fprintf(os.Stderr, "      --color string Style headline numbers and warnings: auto, always or never (default: auto)\n")
+		fmt.Fprintf(os.Stderr, "      --sizing-safety-factor float  Margin for advisory equipment sizing (default: %.2f)\n", config.SizingSafetyFactor)
+		fmt.Fprintf(os.Stderr, "      --operating-hours float  Hours per day conditioned (default: %.0f)\n", config.OperatingHours)
+		fmt.Fprintf(os.Stderr, "      --operating-days float   Days per week conditioned (default: %.0f)\n", config.OperatingDays)
+		fmt.Fprintf(os.Stderr, "      --calendar string   Open weekdays, e.g. mon-fri; replaces --operating-days\n")
+		fmt.Fprintf(os.Stderr, "      --holidays path     Closure dates (YYYY-MM-DD per line) excluded from the calendar\n")
+		fmt.Fprintf(os.Stderr, "      --calendar-year int Year the calendar counts (default: this year)\n")
+		fmt.Fprintf(os.Stderr, "      --window-area float Glazed area in m² to estimate reduction when -r is omitted\n")
+		fmt.Fprintf(os.Stderr, "      --wall-area float   Wall area in m²: the window area is --wwr times it when not\n")
+		fmt.Fprintf(os.Stderr, "                          given, and a --window-area far from that is warned about\n")
+		fmt.Fprintf(os.Stderr, "      --wwr-tolerance float  Relative gap warned about (default: %.2f)\n", config.WWRTolerance)
+		fmt.Fprintf(os.Stderr, "      --floor-area float  Floor area in m² for the savings per m² of floor\n")
+		fmt.Fprintf(os.Stderr, "      --eplus-csv path    EnergyPlus eplusout.csv to sum the reduction from, with\n")
+		fmt.Fprintf(os.Stderr, "      --eplus-column name the column header or a unique part of it\n")
+		fmt.Fprintf(os.Stderr, "      --shgc-existing float  Retrofit: SHGC before; with --shgc-proposed and\n")
+		fmt.Fprintf(os.Stderr, "      --shgc-proposed float  --window-area, derives the reduction instead of -r\n")
+		fmt.Fprintf(os.Stderr, "      --film name         Bundled film for --shgc-proposed and its installed cost (--list-films)\n")
+		fmt.Fprintf(os.Stderr, "      --compare-film list Rank bundled films by payback\n")
+		fmt.Fprintf(os.Stderr, "      --diff-baseline     With the retrofit SHGCs, report savings beyond code minimum\n")
+		fmt.Fprintf(os.Stderr, "      --code-shgc float   Code-minimum SHGC (default: Title 24 or IECC for --location)\n")
+		fmt.Fprintf(os.Stderr, "      --lat, --lng float  Site coordinates for NREL irradiance lookup\n")
+		fmt.Fprintf(os.Stderr, "      --nrel-api-key str  NREL API key (falls back to bundled irradiance)\n")
+		fmt.Fprintf(os.Stderr, "      --roof-area float   Roof area in m² for the cool-roof component (default: off)\n")
+		fmt.Fprintf(os.Stderr, "      --roof-absorptance float           Existing roof absorptance (default: %.2f)\n", config.RoofAbsorptance)
+		fmt.Fprintf(os.Stderr, "      --roof-absorptance-proposed float  Proposed roof absorptance (default: %.2f)\n", config.RoofAbsorptanceProposed)
+		fmt.Fprintf(os.Stderr, "      --roof-u-factor float              Roof U-factor in W/m²K (default: %.2f)\n", config.RoofUFactor)
+		fmt.Fprintf(os.Stderr, "      --annual-bill float Annual electricity bill in $ to frame the savings against\n")
+		fmt.Fprintf(os.Stderr, "      --baseline-emissions float  Annual kg CO2e to frame the CO2 avoided against\n")
+		fmt.Fprintf(os.Stderr, "      --carbon-price float  $/tonne CO2e the CO2 avoided is worth (default: off)\n")
+		fmt.Fprintf(os.Stderr, "      --project-cost float  Installed cost in $ for the simple payback\n")
+		fmt.Fprintf(os.Stderr, "      --dr-incentive float  Demand response $/kW-year, as separate revenue (default: off)\n")
+		fmt.Fprintf(os.Stderr, "      --coincidence-factor float  Share of the peak kW cut at the utility peak (default: %.2f)\n", config.CoincidenceFactor)
+		fmt.Fprintf(os.Stderr, "      --state string US state to estimate cost when -c is omitted, e.g. CA\n")
+		fmt.Fprintf(os.Stderr, "      --eia-api-key str   EIA API key (falls back to bundled state prices)\n")
+		fmt.Fprintf(os.Stderr, "      --cache-ttl age     Refetch cached NREL/EIA data older than this (default: %s; 0 never)\n", config.CacheTTL)
+		fmt.Fprintf(os.Stderr, "      --start-date string Report savings foregone since this date (YYYY-MM-DD)\n")
+		fmt.Fprintf(os.Stderr, "      --cost-hook path    External cost model replacing cost x kWh (JSON in, $/year out)\n")
+		fmt.Fprintf(os.Stderr, "      --heating-mode      Heating climates: price added gain (negative -r) as heating savings\n")
+		fmt.Fprintf(os.Stderr, "      --heating-cop float Heating COP or efficiency (default: %.1f)\n", config.HeatingCOP)
+		fmt.Fprintf(os.Stderr, "      --heating-cost float  Heating energy cost in $/kWh (default: --cost)\n")
+		fmt.Fprintf(os.Stderr, "      --heating-season-fraction float  Share of the year blocked gain would offset\n")
+		fmt.Fprintf(os.Stderr, "                          heating, subtracted as a heating penalty (default: off)\n")
+		fmt.Fprintf(os.Stderr, "      --baseline-config path  Reference scenario config to report the differences against\n")
+		fmt.Fprintf(os.Stderr, "      --require-positive-net  Fail when the heating penalty outweighs the savings\n")
+		fmt.Fprintf(os.Stderr, "      --pv-offset-fraction float  Advanced: share of cooling electricity met by\n")
+		fmt.Fprintf(os.Stderr, "                          on-site PV; only the grid share is priced (default: off)\n")
+		fmt.Fprintf(os.Stderr, "      --name string       Label for the scenario in the report, JSON and CSV\n")
+		fmt.Fprintf(os.Stderr, "  -l, --location string   Building location; sets the default grid CO2 rate and\n")
+		fmt.Fprintf(os.Stderr, "                          fallback irradiance (default: %s)\n", config.Location)
+		fmt.Fprintf(os.Stderr, "      --grid-co2 float    Grid kg CO2e/kWh, overriding the location default\n")
+		fmt.Fprintf(os.Stderr, "      --emissions-basis string  average: every kWh at the grid mix; marginal: at the\n")
+		fmt.Fprintf(os.Stderr, "                          generation a saved kWh displaces (default: %s)\n", config.EmissionsBasis)
+		fmt.Fprintf(os.Stderr, "      --marginal-co2 float  Marginal kg CO2e/kWh, overriding the location default\n")
+		fmt.Fprintf(os.Stderr, "  -o, --output string     Output directory (default: %s)\n", config.OutputDir)
+		fmt.Fprintf(os.Stderr, "      --gzip              Gzip the JSON and CSV output files\n")
+		fmt.Fprintf(os.Stderr, "      --csv-delimiter char   CSV field delimiter, e.g. ';' (default: ,)\n")
+		fmt.Fprintf(os.Stderr, "      --output-timestamp time  Fixed RFC 3339 time for results and file names\n")
+		fmt.Fprintf(os.Stderr, "      --json-compact Write minified JSON files for archives and programs\n")
+		fmt.Fprintf(os.Stderr, "      --json-with-units Write numeric results as {value, units} objects\n")
+		fmt.Fprintf(os.Stderr, "      --csv-crlf End CSV lines with CRLF for Windows tools\n")
+		fmt.Fprintf(os.Stderr, "      --columns names     CSV columns to write, in order (default: all):\n")
+		fmt.Fprintf(os.Stderr, "                          %s\n", strings.Join(csvColumnNames, ","))
+		fmt.Fprintf(os.Stderr, "      --dump-intermediates  Add every intermediate quantity to the JSON output\n")
+		fmt.Fprintf(os.Stderr, "      --append-csv path   Append each run to a CSV log\n")
+		fmt.Fprintf(os.Stderr, "      --ndjson path       Append each run to a newline-delimited JSON log\n")
+		fmt.Fprintf(os.Stderr, "      --audit-log path    Append every calculation with its full inputs (append-only)\n")
+		fmt.Fprintf(os.Stderr, "      --config string     YAML or JSON config file, overridden by flags; repeat to\n")
+		fmt.Fprintf(os.Stderr, "                          layer files, later files overriding earlier ones\n\n")
+		fmt.Fprintf(os.Stderr, "Other Options:\n")
+		fmt.Fprintf(os.Stderr, "  -i, --interactive      Prompt for missing required values\n")
+		fmt.Fprintf(os.Stderr, "      --print-config     Print the effective configuration and exit\n")
+		fmt.Fprintf(os.Stderr, "      --strict           Fail unless every assumption is set explicitly\n")
+		fmt.Fprintf(os.Stderr, "      --merge strings    Combine result JSON files into one report and exit\n")
+		fmt.Fprintf(os.Stderr, "      --watch            Recompute on every save of a --config file\n")
+		fmt.Fprintf(os.Stderr, "      --validate-only    Same as the validate command\n")
+		fmt.Fprintf(os.Stderr, "      --headline-only    Print one bare number, e.g. for $(...); no files without -o\n")
+		fmt.Fprintf(os.Stderr, "      --headline-metric string  %s (default: annual-savings)\n",
+			strings.Join(headlineNames(), ", "))
+		fmt.Fprintf(os.Stderr, "      --template path    Format the result with a text/template file, e.g.\n")
+		fmt.Fprintf(os.Stderr, "                         examples/templates/summary.tmpl\n")
+		fmt.Fprintf(os.Stderr, "      --measured-bills path  Monthly kWh before/after: realized savings vs the model\n")
+		fmt.Fprintf(os.Stderr, "      --manifest path     Write the inputs, their sources and references (.md or JSON)\n")
+		fmt.Fprintf(os.Stderr, "      --bundle name.zip   Zip the results, report and manifest with an index.txt\n")
+		fmt.Fprintf(os.Stderr, "      --target-savings usd  Solve for the SHGC (or --solve-for reduction) meeting a target\n")
+		fmt.Fprintf(os.Stderr, "      --sanity Compare the inputs with typical clinic ranges and exit\n")
+		fmt.Fprintf(os.Stderr, "      --compare-cop list Savings side by side at several COPs, e.g. 3.0,4.0,5.5\n")
+		fmt.Fprintf(os.Stderr, "      --compare-wwr list Savings at several WWRs over --wall-area, e.g. 0.2,0.4,0.6\n")
+		fmt.Fprintf(os.Stderr, "      --compare-locations list  Rank the building in several cities, e.g.\n")
+		fmt.Fprintf(os.Stderr, "                         Phoenix,Sacramento,Seattle, as a table and CSV\n")
+		fmt.Fprintf(os.Stderr, "      --table-rows, --table-cols key=start:stop:step\n")
+		fmt.Fprintf(os.Stderr, "                         Write a CSV matrix of annual savings over two inputs\n")
+		fmt.Fprintf(os.Stderr, "      --tornado Sensitivity of savings to ±10%% in each input, as a table and CSV\n")
+		fmt.Fprintf(os.Stderr, "      --monte-carlo n    Savings p10-p90 and payback p50/p90 over n samples of the inputs\n")
+		fmt.Fprintf(os.Stderr, "      --shadow-price list  Savings at projected prices, e.g. 2025=0.15,2030=0.19 or a\n")
+		fmt.Fprintf(os.Stderr, "                         range 0.10:0.30:0.05, as a table and CSV\n")
+		fmt.Fprintf(os.Stderr, "      --report-since dur Summarize --append-csv rows from the last 30d, 2w, 12h...\n")
+		fmt.Fprintf(os.Stderr, "      --fail-on-warning  Exit 1 if any warning was printed (for CI)\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose          Show detailed assumptions and calculations\n")
+		fmt.Fprintf(os.Stderr, "  -V, --version          Show program version\n\n")
+		fmt.Fprintf(os.Stderr, "Environment:\n")
+		fmt.Fprintf(os.Stderr, "  Every flag except the list flags can be set as %s<FLAG>, upper-cased with\n", envPrefix)
+		fmt.Fprintf(os.Stderr, "  - as _, e.g. %s=4.2 or %s=0.3. Flags override\n", envVar("cop"), envVar("time-lag-factor"))
+		fmt.Fprintf(os.Stderr, "  them and they override --config files.\n\n")
+		fmt.Fprintf(os.Stderr, "Examples:\n")
+		fmt.Fprintf(os.Stderr, "  calculator -r 100 -c 0.15\n")
+		fmt.Fprintf(os.Stderr, "  calculator calc --reduction 150.5 --cost 0.12 --cop 3.5 --shgc 0.3 -o results\n")
+		fmt.Fprintf(os.Stderr, "  calculator --merge 'results/solar_cooling_2*.json' -o merged\n")
+	}
+
+	fs.Parse(args)
+	defer func() { status = failOnWarnings(failOnWarn, status) }()
+
+	// Config files and SOLARCALC_ variables apply to every flag, including
+	// those of the early exits below.
+	flags.record()
+	fileKeys, err := flags.load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	colorWarnings(config.Color)
+
+	var tmpl *template.Template
+	if tmplPath != "" {
+		var err error
+		if tmpl, err = loadTemplate(tmplPath); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if showVersion {
+		fmt.Printf("Solar Cooling Energy Calculator v%s\n", version)
+		return 0
+	}
+
+	format, err := outputFormatOf(config)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if len(merge) > 0 {
+		jsonPath, csvPath, err := mergeResults(merge, config.OutputDir, format, warnings)
+		if err != nil {
+			fmt.Printf("Error merging results: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Merged results written to %s and %s\n", jsonPath, csvPath)
+		return 0
+	}
+
+	if reportSince != "" {
+		if appendCSV == "" {
+			fmt.Println("Error: --report-since requires --append-csv")
+			return 1
+		}
+		window, err := parseSince(reportSince)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		file, err := os.Open(appendCSV)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		summary, err := summarizeSince(file, format.Comma, window, time.Now(), warnings)
+		file.Close()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		printTrailingSummary(os.Stdout, summary)
+		return 0
+	}
+
+	// Every mode from here on models the scenario, so --strict applies to
+	// all of them.
+	if config.Strict {
+		if missing := unaffirmedAssumptions(config, flags.sources(fileKeys)); len(missing) > 0 {
+			fmt.Println("Error: --strict requires these assumptions to be set explicitly:")
+			for _, name := range missing {
+				fmt.Printf("  --%s\n", name)
+			}
+			return 1
+		}
+	}
+
+	openAuditLog(auditPath, "calc")
+
+	// progress takes the notes printed on the way to the result, which
+	// --headline-only keeps off stdout.
+	var progress io.Writer = os.Stdout
+	if headline {
+		if tmpl != nil {
+			fmt.Println("Error: --headline-only replaces --template; give one or the other")
+			return 1
+		}
+		progress = os.Stderr
+	}
+
+	if watch {
+		if len(flags.paths) == 0 {
+			fmt.Println("Error: --watch requires --config")
+			return 1
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		err := watchFiles(ctx, flags.paths, func() {
+			fmt.Print("\033[H\033[2J")
+			if fileKeys, err := flags.load(); err != nil {
+				printErrors(err)
+			} else if result, scenario, err := runScenario(config, flags.sources(fileKeys), os.Stdout); err != nil {
+				printErrors(err)
+			} else {
+				printResult(os.Stdout, result, scenario, verbose)
+			}
+			fmt.Printf("\nWatching %s for changes (Ctrl-C to exit)\n", strings.Join(flags.paths, ", "))
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if dumpConfig {
+		if err := printConfig(os.Stdout, config, flags.sources(fileKeys)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if validate {
+		return reportValidation(config, flags.sources(fileKeys))
+	}
+
+	if listFilms {
+		printFilms(os.Stdout)
+		return 0
+	}
+
+	if len(compareFilm) > 0 {
+		if config.Film != "" {
+			fmt.Println("Error: --compare-film replaces --film; give one or the other")
+			return 1
+		}
+		rows, err := compareFilms(config, compareFilm, flags.sources(fileKeys))
+		if err != nil {
+			printErrors(err)
+			return 1
+		}
+		printFilmComparison(os.Stdout, rows)
+		return 0
+	}
+
+	if len(compareLocs) > 0 {
+		rows, err := compareLocations(config, compareLocs, flags.sources(fileKeys))
+		if err != nil {
+			printErrors(err)
+			return 1
+		}
+		if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+		// Every scenario passed Validate, which checks the format.
+		format, _ := outputFormatOf(config)
+		path, err := saveLocationComparison(config.OutputDir, rows, format)
+		if err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+		unit, _ := lookupCO2Unit(config.CO2Unit)
+		printLocationComparison(os.Stdout, rows, unit)
+		fmt.Printf("\nLocation comparison saved to %s\n", path)
+		return 0
+	}
+
+	if len(compareWWR) > 0 {
+		rows, err := compareWWRs(config, compareWWR, flags.sources(fileKeys))
+		if err != nil {
+			printErrors(err)
+			return 1
+		}
+		printWWRComparison(os.Stdout, rows)
+		return 0
+	}
+
+	solar := newSolarLookup(&config, warnings, progress)
+
+	if err := estimateInputs(&config, flags.sources(fileKeys), solar, progress); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if interactive && stdinIsTerminal() {
+		if err := promptMissing(&config, flags.changed, os.Stdin, os.Stdout); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if tableRows != "" || tableCols != "" {
+		return runPrecomputeTable(config, tableRows, tableCols, solar)
+	}
+
+	if billsPath != "" {
+		return runMeasured(config, billsPath)
+	}
+
+	if target != 0 {
+		return runSolve(config, solveFor, target)
+	}
+
+	if err := config.Validate(); err != nil {
+		printErrors(err)
+		if config.SolarReduction == 0 || (config.ElectricityCost <= 0 && config.HeatingCost <= 0 && config.CostHook == "") {
+			fs.Usage()
+		}
+		return 1
+	}
+	printWarnings(warnings, config)
+	if headline {
+		if err := checkHeadlineMetric(metric, config); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if sanity {
+		resource, err := solar()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		printSanity(os.Stdout, sanityChecks(config, resource.AnnualGHI))
+		return 0
+	}
+
+	if len(compareCOP) > 0 {
+		rows, err := compareCOPs(config, compareCOP, solar)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		printCOPComparison(os.Stdout, rows)
+		return 0
+	}
+
+	if monteCarloN != 0 {
+		if monteCarloN < 0 {
+			fmt.Println("Error: --monte-carlo must be a positive number of samples")
+			return 1
+		}
+		risk, err := monteCarlo(config, monteCarloN, inputConfidence(config, flags.sources(fileKeys)), solar)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		printMonteCarlo(os.Stdout, risk)
+		return 0
+	}
+
+	if shadowPrice != "" {
+		points, err := parsePricePoints(shadowPrice)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		rows, err := shadowPrices(config, points, solar)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		today, err := computeResult(config, solar)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+		format, _ := outputFormatOf(config)
+		path, err := saveShadowPrices(config.OutputDir, rows, format)
+		if err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+		printShadowPrices(os.Stdout, rows, config, today.NetAnnualSavings)
+		fmt.Printf("\nPrice table saved to %s\n", path)
+		return 0
+	}
+
+	if sensitivity {
+		rows, base, err := tornado(config, solar)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+		format, _ := outputFormatOf(config)
+		path, err := saveTornado(config.OutputDir, rows, format)
+		if err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+		printTornado(os.Stdout, rows, base)
+		fmt.Printf("\nSensitivity table saved to %s\n", path)
+		return 0
+	}
+
+	// --headline-only writes no files unless -o asks for them.
+	save := !headline || bundle != "" || flags.sources(fileKeys)["output_dir"] != "default"
+	if save {
+		if err := checkOutputDir(config.OutputDir); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+	result, err := computeResult(config, solar)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	result.Confidence = inputConfidence(config, flags.sources(fileKeys))
+	if baseline != "" {
+		reference, err := loadBaselineConfig(baseline)
+		if err != nil {
+			printErrors(err)
+			return 1
+		}
+		result.Baseline = baselineDelta(baseline, result, reference)
+	}
+	if err := audit.record(result, config); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if positiveNet {
+		if err := checkPositiveNet(result); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	var jsonPath, csvPath string
+	if save {
+		if jsonPath, csvPath, err = saveResults(result, config); err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+	}
+
+	if appendCSV != "" {
+		// Validate has checked the format, which a config file may set.
+		format, _ := outputFormatOf(config)
+		if err := appendCSVRow(appendCSV, newResultOutput(result, config), format); err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+	}
+
+	if ndjson != "" {
+		if err := appendNDJSON(ndjson, newResultOutput(result, config)); err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+	}
+
+	if manifest != "" {
+		if err := writeManifest(manifest, assumptionsManifest(config, flags.sources(fileKeys), result)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if bundle != "" {
+		path := bundlePath(config.OutputDir, bundle)
+		files, err := bundleFiles(result, config, flags.sources(fileKeys), jsonPath, csvPath, manifest, tmplPath, tmpl)
+		if err == nil {
+			err = writeBundle(path, files, reproduceCommand(config), outputTime(config))
+		}
+		if err != nil {
+			fmt.Printf("Error saving results: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(progress, "Bundle written to %s\n", path)
+	}
+
+	switch {
+	case headline:
+		fmt.Println(headlineValue(metric, result, config))
+		return 0
+	case tmpl != nil:
+		if err := tmpl.Execute(os.Stdout, newResultOutput(result, config)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	default:
+		printResult(os.Stdout, result, config, verbose)
+	}
+	if verbose {
+		fmt.Printf("\nResults saved to %s and %s\n", jsonPath, csvPath)
+	}
+	return 0
+}
+
+// runMeasured is calc's --measured-bills mode. The reduction is optional
+// since the bills replace it; the other inputs are validated as usual.
+func runMeasured(config Config, path string) int {
+	bills, err := loadBills(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	check := config
+	if check.SolarReduction == 0 {
+		check.SolarReduction = 1
+	}
+	if err := check.Validate(); err != nil {
+		printErrors(err)
+		return 1
+	}
+	printMeasuredSavings(os.Stdout, measureSavings(config, bills), config)
+	return 0
+}
+
+// runSolve is calc's --target-savings mode. As with --measured-bills the
+// reduction may be left out, here when it is the input solved for. An
+// infeasible target exits 1 after it is reported.
+func runSolve(config Config, input string, target float64) int {
+	if target < 0 {
+		fmt.Println("Error: --target-savings must be positive")
+		return 1
+	}
+	check := config
+	if input == "reduction" && check.SolarReduction == 0 {
+		check.SolarReduction = 1
+	}
+	if err := check.Validate(); err != nil {
+		printErrors(err)
+		return 1
+	}
+	d, err := solveTarget(config, input, target)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	printDesignTarget(os.Stdout, d)
+	if !d.Feasible() {
+		return 1
+	}
+	return 0
+}
+
+// runPrecomputeTable is calc's --table-rows/--table-cols mode.
+func runPrecomputeTable(config Config, rowSpec, colSpec string, solar func() (SolarResource, error)) int {
+	if rowSpec == "" || colSpec == "" {
+		fmt.Println("Error: --table-rows and --table-cols must be given together")
+		return 1
+	}
+	rows, err := parseTableAxis(rowSpec)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	cols, err := parseTableAxis(colSpec)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	format, err := outputFormatOf(config)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	grid, err := precomputeTable(config, rows, cols, solar)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+		fmt.Printf("Error saving results: %v\n", err)
+		return 1
+	}
+	path, err := saveTable(config.OutputDir, rows, cols, grid, format)
+	if err != nil {
+		fmt.Printf("Error saving results: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%d x %d savings table written to %s\n", len(rows.Values), len(cols.Values), path)
+	return 0
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout runs f with os.Stdout redirected, returning what it wrote.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestStrictRefusesDefaultsInEveryMode(t *testing.T) {
+	base := []string{"--strict", "-r", "100", "-c", "0.15", "--color", "never", "-o", t.TempDir(),
+		"--cop", "4", "--shgc", "0.25", "--wwr", "0.4", "--transmission-factor", "0.8", "--medical-equip-factor", "1.15"}
+	tests := []struct {
+		name    string
+		args    []string
+		refused bool
+	}{
+		{"print config", []string{"--print-config"}, true},
+		{"validate only", []string{"--validate-only"}, true},
+		{"sanity", []string{"--sanity"}, true},
+		{"compare COPs", []string{"--compare-cop", "3,4"}, true},
+		{"compare WWRs", []string{"--compare-wwr", "0.2,0.4"}, true},
+		{"compare locations", []string{"--compare-locations", "Phoenix"}, true},
+		{"target savings", []string{"--target-savings", "500"}, true},
+		{"thermal mass for the time lag", []string{"--thermal-mass", "medium", "--print-config"}, false},
+		{"time lag given", []string{"--time-lag-factor", "0.95", "--print-config"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status int
+			out := captureStdout(t, func() { status = runCalc(append(append([]string{}, base...), tt.args...)) })
+			refused := strings.Contains(out, "--strict requires these assumptions to be set explicitly:\n  --time-lag-factor\n")
+			if refused != tt.refused || refused && status != 1 {
+				t.Errorf("status %d, output:\n%s\nwant refused: %v", status, out, tt.refused)
+			}
+			if !tt.refused && status != 0 {
+				t.Errorf("status %d, output:\n%s", status, out)
+			}
+		})
+	}
+}
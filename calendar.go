@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseWeekdays parses a calendar such as "mon-fri", "mon-fri,sat" or
+// "weekdays" into the set of open weekdays. Ranges may wrap, e.g. "sat-mon".
+func parseWeekdays(spec string) (map[time.Weekday]bool, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "", "all", "daily":
+		spec = "sun-sat"
+	case "weekdays":
+		spec = "mon-fri"
+	}
+
+	open := make(map[time.Weekday]bool)
+	for _, item := range strings.Split(strings.ToLower(spec), ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		if !isRange {
+			to = from
+		}
+		first, ok1 := weekdayNames[shortDay(from)]
+		last, ok2 := weekdayNames[shortDay(to)]
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("invalid calendar %q: expected days like mon-fri or mon,wed,fri", spec)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			open[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return open, nil
+}
+
+func shortDay(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > 3 {
+		name = name[:3]
+	}
+	return name
+}
+
+// readHolidays reads closure dates, one YYYY-MM-DD per line. Anything
+// after the date on a line (a comma, a name) is ignored, as are blank
+// lines and lines starting with #.
+func readHolidays(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holidays file: %v", err)
+	}
+	defer file.Close()
+
+	holidays := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		date := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })[0]
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid date %q", path, line, date)
+		}
+		holidays[date] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read holidays file: %v", err)
+	}
+	return holidays, nil
+}
+
+// calendarOperatingDays counts the days in year that fall on an open
+// weekday and are not holidays, along with the days in that year.
+func calendarOperatingDays(year int, open map[time.Weekday]bool, holidays map[string]bool) (int, int) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	days, total := 0, 0
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		total++
+		if open[d.Weekday()] && !holidays[d.Format(time.DateOnly)] {
+			days++
+		}
+	}
+	return days, total
+}
+
+// applyCalendar replaces OperatingDays with the weekly average implied by
+// the calendar, so that the annual multiplier counts the days the
+// building is actually open. It returns the operating days in the year.
+func applyCalendar(config *Config) (int, error) {
+	open, err := parseWeekdays(config.Calendar)
+	if err != nil {
+		return 0, err
+	}
+	holidays := map[string]bool{}
+	if config.Holidays != "" {
+		if holidays, err = readHolidays(config.Holidays); err != nil {
+			return 0, err
+		}
+	}
+	if config.CalendarYear == 0 {
+		config.CalendarYear = time.Now().Year()
+	}
+
+	days, total := calendarOperatingDays(config.CalendarYear, open, holidays)
+	config.OperatingDays = 7 * float64(days) / float64(total)
+	config.OperatingDaysPerYear = days
+	return days, nil
+}
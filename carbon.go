@@ -0,0 +1,86 @@
+package main
+
+import "fmt"
+
+// nationalGridCO2 is the US average grid emission rate in kg CO2e/kWh
+// (eGRID2022), used when the location is not in the bundled table.
+const nationalGridCO2 = 0.37
+
+// nationalMarginalCO2 is the US non-baseload emission rate in kg
+// CO2e/kWh (eGRID2022), the marginal counterpart of nationalGridCO2.
+const nationalMarginalCO2 = 0.59
+
+// resolveGridCO2 returns the emission rate for config's emissions basis
+// and where it came from: the --grid-co2 or --marginal-co2 override, the
+// location's eGRID subregion, or the national rate.
+//
+// The average basis prices every saved kWh at the grid's mix, including
+// the baseload nuclear, hydro and renewables that run regardless of
+// demand. A daytime cooling reduction instead displaces the generation
+// that follows load, usually gas or coal, so the marginal basis uses the
+// non-baseload rate and reports more CO2 avoided on most grids.
+func resolveGridCO2(config Config) (float64, string) {
+	if config.EmissionsBasis == "marginal" {
+		if config.MarginalCO2 > 0 {
+			return config.MarginalCO2, "input"
+		}
+		if data, ok := lookupLocation(config.Location); ok && data.MarginalCO2 > 0 {
+			return data.MarginalCO2, fmt.Sprintf("eGRID2022 %s non-baseload (%s)", data.Subregion, config.Location)
+		}
+		return nationalMarginalCO2, "eGRID2022 US non-baseload"
+	}
+	if config.GridCO2 > 0 {
+		return config.GridCO2, "input"
+	}
+	if data, ok := lookupLocation(config.Location); ok && data.GridCO2 > 0 {
+		return data.GridCO2, fmt.Sprintf("eGRID2022 %s (%s)", data.Subregion, config.Location)
+	}
+	return nationalGridCO2, "eGRID2022 US average"
+}
+
+// applyEmissions sets the CO2 avoided by the grid electricity the result
+// saves, the windows' and any cool roof's over the coolingSeason, so it
+// must follow the roof. In heating mode the heating is assumed to be
+// electric.
+func applyEmissions(result *Result, config Config) {
+	intensity, source := resolveGridCO2(config)
+	result.EmissionsBasis = config.EmissionsBasis
+	result.GridCO2 = intensity
+	result.GridCO2Source = source
+	season := coolingSeason(config)
+	gridSaved, annualSaved := result.GridElectricitySaved, result.GridElectricitySaved*season
+	if result.Roof != nil {
+		roof := result.Roof.ElectricitySaved * gridFraction(config)
+		gridSaved += roof
+		annualSaved += roof * season
+	}
+	result.CO2Avoided = gridSaved * intensity
+	result.AnnualCO2Avoided = annualSaved * intensity * 365 * result.OperatingFactor
+	if config.BaselineEmissions > 0 {
+		result.PercentEmissionsReduced = 100 * result.AnnualCO2Avoided / config.BaselineEmissions
+	}
+	result.CarbonValue = result.AnnualCO2Avoided / 1000 * config.CarbonPrice
+}
+
+// co2Unit is a unit the CO2 avoided may be displayed in.
+type co2Unit struct {
+	Label    string
+	PerKg    float64 // units per kg
+	Decimals int     // default display precision
+}
+
+// co2Units are the --co2-unit choices. JSON always carries kg.
+var co2Units = map[string]co2Unit{
+	"kg":    {Label: "kg", PerKg: 1, Decimals: 1},
+	"tonne": {Label: "t", PerKg: 0.001, Decimals: 3},
+	"lb":    {Label: "lb", PerKg: 2.20462, Decimals: 0},
+}
+
+// lookupCO2Unit returns the display unit named by config, kg when unset.
+func lookupCO2Unit(name string) (co2Unit, bool) {
+	if name == "" {
+		name = "kg"
+	}
+	unit, ok := co2Units[name]
+	return unit, ok
+}
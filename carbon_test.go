@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEmissionsCoverEveryGridKWhSaved(t *testing.T) {
+	const ghi = 5.1
+	solar := func() (SolarResource, error) { return SolarResource{AnnualGHI: ghi}, nil }
+	tests := []struct {
+		name      string
+		config    func(c *Config)
+		intensity float64 // kg CO2e/kWh
+	}{
+		{"location average", func(c *Config) {}, 0.23},
+		{"location marginal", func(c *Config) { c.EmissionsBasis = "marginal" }, 0.41},
+		{"override", func(c *Config) { c.GridCO2 = 0.5 }, 0.5},
+		{"marginal override", func(c *Config) { c.EmissionsBasis, c.MarginalCO2 = "marginal", 0.7 }, 0.7},
+		{"unknown location", func(c *Config) { c.Location = "Nowhere" }, nationalGridCO2},
+		{"unknown location marginal", func(c *Config) { c.Location, c.EmissionsBasis = "Nowhere", "marginal" }, nationalMarginalCO2},
+		{"cool roof", func(c *Config) { c.RoofArea = 200 }, 0.23},
+		{"roof behind PV", func(c *Config) { c.RoofArea, c.PVOffsetFraction = 200, 0.4 }, 0.23},
+		{"part-time", func(c *Config) { c.RoofArea, c.OperatingHours, c.OperatingDays = 200, 10, 5 }, 0.23},
+		{"carbon price", func(c *Config) { c.CarbonPrice, c.BaselineEmissions = 50, 20000 }, 0.23},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.SolarReduction, config.ElectricityCost = 100, 0.15
+			tt.config(&config)
+			result, err := computeResult(config, solar)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gridKWh := result.GridElectricitySaved
+			if config.RoofArea > 0 {
+				gridKWh += calculateRoofSavings(config, ghi).ElectricitySaved * (1 - config.PVOffsetFraction)
+			}
+			annual := gridKWh * tt.intensity * 365 * (config.OperatingHours / 24) * (config.OperatingDays / 7)
+			if result.GridCO2 != tt.intensity {
+				t.Errorf("GridCO2 = %g, want %g", result.GridCO2, tt.intensity)
+			}
+			if math.Abs(result.AnnualCO2Avoided-annual) > 1e-9 {
+				t.Errorf("AnnualCO2Avoided = %g, want %g", result.AnnualCO2Avoided, annual)
+			}
+			if want := annual / 1000 * config.CarbonPrice; math.Abs(result.CarbonValue-want) > 1e-9 {
+				t.Errorf("CarbonValue = %g, want %g", result.CarbonValue, want)
+			}
+			if config.BaselineEmissions > 0 {
+				if want := 100 * annual / config.BaselineEmissions; math.Abs(result.PercentEmissionsReduced-want) > 1e-9 {
+					t.Errorf("PercentEmissionsReduced = %g, want %g", result.PercentEmissionsReduced, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCodeBaselineLeavesTheRoofOutOfTheIncrement(t *testing.T) {
+	solar := func() (SolarResource, error) { return SolarResource{AnnualGHI: 5.1}, nil }
+	config := DefaultConfig()
+	config.SolarReduction, config.ElectricityCost, config.DiffBaseline = 100, 0.15, true
+	windows, err := computeResult(config, solar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.RoofArea = 200
+	withRoof, err := computeResult(config, solar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withRoof.AnnualCO2Avoided <= windows.AnnualCO2Avoided {
+		t.Errorf("AnnualCO2Avoided with a roof = %g, want more than %g", withRoof.AnnualCO2Avoided, windows.AnnualCO2Avoided)
+	}
+	got, want := withRoof.CodeBaseline.IncrementalCO2Avoided, windows.CodeBaseline.IncrementalCO2Avoided
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("IncrementalCO2Avoided with a roof = %g, want %g", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// colorModes are the --color choices: auto styles output only when it
+// goes to an interactive terminal and NO_COLOR is unset.
+var colorModes = []string{"auto", "always", "never"}
+
+// isTerminal reports whether f is an interactive terminal rather than a
+// pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled reports whether output to w is styled under mode. Only a
+// terminal is styled in auto mode, so piped and redirected output is
+// always plain.
+func colorEnabled(mode string, w io.Writer) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// ansiStyle wraps text in an ANSI escape sequence when on.
+type ansiStyle struct {
+	on bool
+}
+
+func (s ansiStyle) wrap(code, text string) string {
+	if !s.on {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+// headline marks the main result numbers.
+func (s ansiStyle) headline(text string) string { return s.wrap("1;32", text) }
+
+// warning marks a warning line.
+func (s ansiStyle) warning(text string) string { return s.wrap("33", text) }
+
+// colorWarnings styles the warnings written to stderr under mode.
+func colorWarnings(mode string) {
+	if l, ok := warnings.(*warningLog); ok {
+		l.style = ansiStyle{colorEnabled(mode, l.out)}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// copComparison is one column of a --compare-cop table.
+type copComparison struct {
+	COP    float64
+	Result Result
+}
+
+// compareCOPs computes config at each COP, lowest first. In heating mode
+// the heating COP is varied instead.
+func compareCOPs(config Config, cops []float64, solar func() (SolarResource, error)) ([]copComparison, error) {
+	sorted := append([]float64(nil), cops...)
+	sort.Float64s(sorted)
+
+	rows := make([]copComparison, 0, len(sorted))
+	for _, cop := range sorted {
+		if cop <= 0 {
+			return nil, errors.New("COP values to compare must be positive")
+		}
+		scenario := config
+		if scenario.HeatingMode {
+			scenario.HeatingCOP = cop
+		} else {
+			scenario.AC_COP = cop
+		}
+		result, err := computeResult(scenario, solar)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, copComparison{cop, result})
+	}
+	return rows, nil
+}
+
+// printCOPComparison tabulates the results side by side, one column per
+// COP, with each column's savings relative to the lowest COP.
+func printCOPComparison(w io.Writer, rows []copComparison) {
+	if len(rows) == 0 {
+		return
+	}
+	units := rows[0].Result.Assumptions.Units
+
+	fmt.Fprintf(w, "%-38s", "COP")
+	for _, r := range rows {
+		fmt.Fprintf(w, " %12.2f", r.COP)
+	}
+	fmt.Fprintf(w, "\n%-38s", "Electricity saved ("+units.Electricity+")")
+	for _, r := range rows {
+		fmt.Fprintf(w, " %12.2f", r.Result.ElectricitySaved)
+	}
+	if rows[0].Result.Mode != "heating" {
+		fmt.Fprintf(w, "\n%-38s", "Peak demand reduced ("+units.PeakElectricity+")")
+		for _, r := range rows {
+			fmt.Fprintf(w, " %12.2f", r.Result.PeakElectricityReduced)
+		}
+	}
+	fmt.Fprintf(w, "\n%-38s", "Annual cost savings ("+units.Savings+")")
+	for _, r := range rows {
+		fmt.Fprintf(w, " %12.2f", r.Result.AnnualCostSaved)
+	}
+	fmt.Fprintf(w, "\n%-38s", "vs lowest COP")
+	base := rows[0].Result.AnnualCostSaved
+	for _, r := range rows {
+		if base == 0 {
+			fmt.Fprintf(w, " %12s", "-")
+			continue
+		}
+		fmt.Fprintf(w, " %+11.1f%%", (r.Result.AnnualCostSaved-base)/base*100)
+	}
+	fmt.Fprintln(w)
+}
+
+// locationComparison is one row of a --compare-locations table.
+type locationComparison struct {
+	Location string
+	Result   Result
+	Config   Config // after estimation, for the reduction and price used
+}
+
+// compareLocations runs config at each location, ranked by annual
+// savings. The location drives the bundled irradiance used by an
+// estimated reduction, the grid emission rate and, when no cost was
+// given, the state electricity price; a fixed --reduction does not vary.
+func compareLocations(config Config, names []string, sources map[string]string) ([]locationComparison, error) {
+	rows := make([]locationComparison, 0, len(names))
+	for _, name := range names {
+		data, ok := lookupLocation(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown location %q, expected one of %s", name, strings.Join(locationNames(), ", "))
+		}
+		scenario := config
+		scenario.Location = name
+		// Live irradiance is looked up by coordinates, which belong to
+		// the base location.
+		scenario.Latitude, scenario.Longitude = 0, 0
+		if scenario.ElectricityCost <= 0 {
+			scenario.State = data.State
+		}
+		result, scenario, err := runScenario(scenario, sources, io.Discard)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		rows = append(rows, locationComparison{name, result, scenario})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Result.AnnualCostSaved > rows[j].Result.AnnualCostSaved
+	})
+	return rows, nil
+}
+
+// locationComparisonHeaders are the columns of the location table and CSV.
+var locationComparisonHeaders = []string{
+	"Rank", "Location", "Solar Reduction (kWh/day)", "Electricity Cost ($/kWh)",
+	"Grid CO2 (kg/kWh)", "Annual Cost Saved ($)", "CO2 Avoided (kg/year)",
+}
+
+func locationComparisonRecord(rank int, r locationComparison) []string {
+	return []string{
+		strconv.Itoa(rank), r.Location,
+		fmt.Sprintf("%.2f", r.Config.SolarReduction),
+		fmt.Sprintf("%.3f", r.Config.ElectricityCost),
+		fmt.Sprintf("%.2f", r.Result.GridCO2),
+		fmt.Sprintf("%.2f", r.Result.AnnualCostSaved),
+		fmt.Sprintf("%.1f", r.Result.AnnualCO2Avoided),
+	}
+}
+
+// printLocationComparison tabulates the ranked locations, with the CO2
+// avoided in unit.
+func printLocationComparison(w io.Writer, rows []locationComparison, unit co2Unit) {
+	fmt.Fprintf(w, "%-4s %-14s %12s %10s %10s %14s %14s\n",
+		"Rank", "Location", "Reduction", "$/kWh", "kg CO2/kWh", "Savings $/yr", "CO2 "+unit.Label+"/yr")
+	for i, r := range rows {
+		fmt.Fprintf(w, "%-4d %-14s %12.2f %10.3f %10.2f %14.2f %14.*f\n",
+			i+1, r.Location, r.Config.SolarReduction, r.Config.ElectricityCost,
+			r.Result.GridCO2, r.Result.AnnualCostSaved, unit.Decimals, r.Result.AnnualCO2Avoided*unit.PerKg)
+	}
+}
+
+// saveLocationComparison writes the ranked table as CSV to dir.
+func saveLocationComparison(dir string, rows []locationComparison, format outputFormat) (string, error) {
+	file, err := createOutputFile(filepath.Join(dir,
+		"solar_cooling_locations_"+fileTimestamp(format.Now())+".csv"), format.Gzip)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CSV file: %v", err)
+	}
+
+	writer := newCSVWriter(file, format)
+	writer.Write(locationComparisonHeaders)
+	for i, r := range rows {
+		writer.Write(locationComparisonRecord(i+1, r))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.discard()
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.tmp)
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := commitOutputFiles(file); err != nil {
+		return "", err
+	}
+	return file.path, nil
+}
+
+// wwrComparison is one row of a --compare-wwr table.
+type wwrComparison struct {
+	WWR    float64
+	Result Result
+	Config Config // after estimation, for the window area and reduction
+}
+
+// compareWWRs runs config at each window-to-wall ratio, lowest first.
+// The WWR only reaches the model through the window area it implies over
+// --wall-area, so the reduction and window area must be left to be
+// derived from it.
+func compareWWRs(config Config, wwrs []float64, sources map[string]string) ([]wwrComparison, error) {
+	switch {
+	case config.WallArea <= 0:
+		return nil, errors.New("--compare-wwr needs --wall-area to turn each WWR into a window area")
+	case config.SolarReduction != 0 || config.EPlusCSV != "" || len(config.WindowGroups) > 0:
+		return nil, errors.New("--compare-wwr estimates the reduction from the window area; drop --reduction, --eplus-csv and window_groups")
+	case config.WindowArea != 0:
+		return nil, errors.New("--compare-wwr sets the window area from each WWR; drop --window-area")
+	}
+	sorted := append([]float64(nil), wwrs...)
+	sort.Float64s(sorted)
+
+	rows := make([]wwrComparison, 0, len(sorted))
+	for _, wwr := range sorted {
+		if wwr <= 0 || wwr > 1 {
+			return nil, fmt.Errorf("WWR values to compare must be between 0 and 1, got %g", wwr)
+		}
+		scenario := config
+		scenario.WWR = wwr
+		result, scenario, err := runScenario(scenario, sources, io.Discard)
+		if err != nil {
+			return nil, fmt.Errorf("WWR %g: %w", wwr, err)
+		}
+		rows = append(rows, wwrComparison{wwr, result, scenario})
+	}
+	return rows, nil
+}
+
+// printWWRComparison tabulates the WWRs, one row each, with the savings
+// relative to the lowest.
+func printWWRComparison(w io.Writer, rows []wwrComparison) {
+	if len(rows) == 0 {
+		return
+	}
+	units := rows[0].Result.Assumptions.Units
+	fmt.Fprintf(w, "Over %.1f m² of wall:\n", rows[0].Config.WallArea)
+	fmt.Fprintf(w, "%6s %10s %12s %14s %14s %14s\n",
+		"WWR", "Window m²", "Reduction", "Cooling load", "Savings $/yr", "vs lowest WWR")
+	base := rows[0].Result.NetAnnualSavings
+	for _, r := range rows {
+		change := "-"
+		if base != 0 {
+			change = fmt.Sprintf("%+.1f%%", (r.Result.NetAnnualSavings-base)/base*100)
+		}
+		fmt.Fprintf(w, "%6.2f %10.1f %12.2f %14.2f %14.2f %14s\n",
+			r.WWR, r.Config.WindowArea, r.Config.SolarReduction, r.Result.CoolingLoadReduced,
+			r.Result.NetAnnualSavings, change)
+	}
+	fmt.Fprintf(w, "\nReduction in %s, cooling load in %s.\n", units.SolarRadiation, units.CoolingLoad)
+	fmt.Fprintln(w, "More glass admits more daylight and more solar gain, so the same glazing")
+	fmt.Fprintln(w, "treatment saves more as the WWR grows.")
+}
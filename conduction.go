@@ -0,0 +1,16 @@
+package main
+
+// conductionPower is the conductive heat gain through the windows that
+// the retrofit avoids, in kW thermal: the U-factor improvement times the
+// glazed area and the mean outdoor-indoor temperature difference. Unlike
+// the solar gain it is not lagged or scaled by the equipment factor.
+func conductionPower(config Config) float64 {
+	return config.UFactor * config.WindowArea * config.DeltaT / 1000
+}
+
+// conductionLoad is conductionPower over a day, in kWh thermal/day, on
+// the same daily footing as the solar reduction before the operating
+// schedule is applied.
+func conductionLoad(config Config) float64 {
+	return conductionPower(config) * 24
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Confidence levels accepted in a config file's confidence map.
+const (
+	confidenceMeasured  = "measured"
+	confidenceEstimated = "estimated"
+	confidenceDefault   = "default"
+)
+
+// confidenceInputs are the Config keys that feed the calculation and so
+// carry a confidence level in the output.
+var confidenceInputs = []string{
+	"solar_reduction", "electricity_cost", "ac_cop", "shgc",
+	"transmission_factor", "time_lag_factor", "medical_equip_factor",
+	"operating_hours", "operating_days",
+}
+
+// inputConfidence resolves the confidence of every calculation input. An
+// explicit annotation wins; otherwise values left at DefaultConfig are
+// "default" and anything supplied or derived is "estimated".
+func inputConfidence(config Config, sources map[string]string) map[string]string {
+	values := configValues(config)
+	defaults := configValues(DefaultConfig())
+
+	confidence := make(map[string]string, len(confidenceInputs))
+	for _, key := range confidenceInputs {
+		switch {
+		case config.Confidence[key] != "":
+			confidence[key] = config.Confidence[key]
+		case sources[key] == "default" && values[key] == defaults[key]:
+			confidence[key] = confidenceDefault
+		case key == "electricity_cost" && strings.HasPrefix(config.CostSource, "default:"):
+			// The location's regional rate stands in for a missing cost.
+			confidence[key] = confidenceDefault
+		default:
+			confidence[key] = confidenceEstimated
+		}
+	}
+	return confidence
+}
+
+// defaultedInputs returns the sorted keys whose confidence is "default".
+func defaultedInputs(confidence map[string]string) []string {
+	var keys []string
+	for key, level := range confidence {
+		if level == confidenceDefault {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateConfidence reports unknown keys or levels in a confidence map.
+func validateConfidence(confidence map[string]string) []error {
+	known := make(map[string]bool, len(confidenceInputs))
+	for _, key := range confidenceInputs {
+		known[key] = true
+	}
+
+	keys := make([]string, 0, len(confidence))
+	for key := range confidence {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("Confidence given for unknown input %q", key))
+		}
+		switch confidence[key] {
+		case confidenceMeasured, confidenceEstimated, confidenceDefault:
+		default:
+			errs = append(errs, fmt.Errorf("Confidence for %s must be measured, estimated or default, not %q",
+				key, confidence[key]))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,741 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	ScenarioName            string  `json:"name"` // label for the run in outputs; not part of the input hash
+	Location                string  `json:"location"`
+	OutputDir               string  `json:"output_dir"`
+	Gzip                    bool    `json:"gzip"`
+	DumpIntermediates       bool    `json:"dump_intermediates"`
+	CSVDelimiter            string  `json:"csv_delimiter"`
+	CSVCRLF                 bool    `json:"csv_crlf"`
+	CSVColumns              string  `json:"csv_columns"`      // comma-separated, all when empty
+	JSONCompact             bool    `json:"json_compact"`     // minified JSON result files
+	JSONWithUnits           bool    `json:"json_with_units"`  // {value, units} objects for numeric results
+	OutputTimestamp         string  `json:"output_timestamp"` // RFC 3339 time stamped on outputs instead of now
+	CO2Unit                 string  `json:"co2_unit"`         // kg, tonne or lb for displayed CO2 avoided
+	Color                   string  `json:"color"`            // auto, always or never for terminal styling
+	SigFigs                 int     `json:"sig_figs"`         // significant figures in text and CSV results, 0 for fixed decimals
+	Model                   string  `json:"model"`            // registered calculation model, see registerModel
+	SolarReduction          float64 `json:"solar_reduction"`
+	ElectricityCost         float64 `json:"electricity_cost"`
+	AC_COP                  float64 `json:"ac_cop"`
+	SystemLosses            float64 `json:"system_losses"` // fan and pump power as a fraction of compressor power
+	SystemType              string  `json:"system_type"`   // dx or chilled-water, for the part-load curve
+	LoadFraction            float64 `json:"load_fraction"` // typical part-load ratio of the cooling plant
+	COPMin                  float64 `json:"cop_min"`       // COPs outside [COPMin, COPMax] are rejected
+	COPMax                  float64 `json:"cop_max"`
+	SHGC                    float64 `json:"shgc"`
+	WWR                     float64 `json:"wwr"`
+	TransmissionFactor      float64 `json:"transmission_factor"`
+	TimeLagFactor           float64 `json:"time_lag_factor"`
+	ThermalMass             string  `json:"thermal_mass"` // light, medium or heavy; replaces TimeLagFactor
+	MedicalEquipFactor      float64 `json:"medical_equip_factor"`
+	EquipSchedule           string  `json:"equip_schedule"` // e.g. 08:00-17:00=1.6; medical_equip_factor elsewhere
+	OperatingHours          float64 `json:"operating_hours"`
+	OperatingDays           float64 `json:"operating_days"`
+	LoadShapeFactor         float64 `json:"load_shape_factor"`
+	SizingSafetyFactor      float64 `json:"sizing_safety_factor"`
+	Latitude                float64 `json:"latitude"`
+	Longitude               float64 `json:"longitude"`
+	WindowArea              float64 `json:"window_area"`   // m²
+	WallArea                float64 `json:"wall_area"`     // m² gross exterior wall, to check window_area against wwr
+	WWRTolerance            float64 `json:"wwr_tolerance"` // relative gap between window_area and wwr x wall_area warned about
+	FloorArea               float64 `json:"floor_area"`    // m² conditioned floor, for the savings intensity
+	UFactor                 float64 `json:"u_factor"`      // W/m²K window U-factor improvement, for conduction
+	DeltaT                  float64 `json:"delta_t"`       // K mean outdoor over indoor temperature
+	EPlusCSV                string  `json:"eplus_csv"`     // EnergyPlus output to sum the reduction from
+	EPlusColumn             string  `json:"eplus_column"`
+	NRELAPIKey              string  `json:"-"`
+	State                   string  `json:"state"`
+	CacheTTL                string  `json:"cache_ttl"` // age after which cached NREL/EIA data is refetched, e.g. 30d; 0 never expires
+	RoofArea                float64 `json:"roof_area"` // m²
+	RoofAbsorptance         float64 `json:"roof_absorptance"`
+	RoofAbsorptanceProposed float64 `json:"roof_absorptance_proposed"`
+	RoofUFactor             float64 `json:"roof_u_factor"`      // W/m²K
+	StartDate               string  `json:"start_date"`         // YYYY-MM-DD
+	CostHook                string  `json:"cost_hook"`          // external cost model
+	AnnualBill              float64 `json:"annual_bill"`        // $/year, for PercentOfBill
+	BaselineEmissions       float64 `json:"baseline_emissions"` // kg CO2e/year, for PercentEmissionsReduced
+	ProjectCost             float64 `json:"project_cost"`       // $ installed, for the simple payback
+	DRIncentive             float64 `json:"dr_incentive"`       // $/kW-year paid per kW of coincident peak shaved
+	CoincidenceFactor       float64 `json:"coincidence_factor"` // share of the peak reduction at the utility's peak
+	PVOffsetFraction        float64 `json:"pv_offset_fraction"`
+	GridCO2                 float64 `json:"grid_co2"`        // kg CO2e/kWh, from location when 0
+	EmissionsBasis          string  `json:"emissions_basis"` // average or marginal
+	MarginalCO2             float64 `json:"marginal_co2"`    // kg CO2e/kWh, from location when 0
+	CarbonPrice             float64 `json:"carbon_price"`    // $/tonne CO2e, for CarbonValue; 0 is off
+	HeatingMode             bool    `json:"heating_mode"`
+	HeatingCOP              float64 `json:"heating_cop"`
+	HeatingCost             float64 `json:"heating_cost"`            // $/kWh, electricity cost when 0
+	HeatingSeasonFraction   float64 `json:"heating_season_fraction"` // share of the year blocked gain offsets heating
+	Calendar                string  `json:"calendar"`                // open weekdays, e.g. mon-fri
+	Holidays                string  `json:"holidays"`                // file of closure dates
+	CalendarYear            int     `json:"calendar_year"`
+	LoadShape               string  `json:"load_shape"`    // file of 48 half-hourly load fractions
+	MonthlyCDD              string  `json:"monthly_cdd"`   // file of 12 monthly cooling degree days
+	TOUPeriods              string  `json:"tou_periods"`   // e.g. 16:00-21:00=0.35; rest at electricity_cost
+	SHGCExisting            float64 `json:"shgc_existing"` // retrofit: replaces --reduction
+	SHGCProposed            float64 `json:"shgc_proposed"`
+	Film                    string  `json:"film"`          // bundled film product: sets shgc_proposed and, with window_area, project_cost
+	DiffBaseline            bool    `json:"diff_baseline"` // compare with code-minimum SHGC
+	CodeSHGC                float64 `json:"code_shgc"`     // from location when 0
+
+	// WindowGroups replaces SolarReduction and SHGC with per-group areas
+	// and SHGCs. It can only be set from a config file.
+	WindowGroups []WindowGroup `json:"window_groups,omitempty"`
+
+	// Confidence tags calculation inputs as measured, estimated or
+	// default. It can only be set from a config file.
+	Confidence map[string]string `json:"confidence,omitempty"`
+	EIAAPIKey  string            `json:"-"`
+
+	// CostSource describes where ElectricityCost came from when it was
+	// looked up rather than supplied, e.g. "EIA CA commercial average".
+	CostSource string `json:"-"`
+
+	// LoadShapeValues are the fractions read from LoadShape.
+	LoadShapeValues []float64 `json:"-"`
+
+	// MonthlyCDDValues are the degree days read from MonthlyCDD.
+	MonthlyCDDValues []float64 `json:"-"`
+
+	// OperatingDaysPerYear is the open days counted from the calendar,
+	// when one is given.
+	OperatingDaysPerYear int `json:"-"`
+
+	// Strict requires every modelling assumption to be set explicitly.
+	Strict bool `json:"-"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Location:                "Sacramento",
+		Model:                   defaultModel,
+		AC_COP:                  4.0, // ASHRAE 90.1-2019
+		SystemType:              "dx",
+		LoadFraction:            1,
+		COPMin:                  1.0,  // below resistance heat pumping; a typo
+		COPMax:                  15.0, // beyond any chiller plant
+		SHGC:                    0.25, // CA Title 24 2022
+		WWR:                     0.40, // DOE Reference Building
+		WWRTolerance:            0.20,
+		CacheTTL:                "30d",
+		TransmissionFactor:      0.80,
+		TimeLagFactor:           0.95,
+		MedicalEquipFactor:      1.15,
+		OperatingHours:          24,
+		OperatingDays:           7,
+		LoadShapeFactor:         1.0,  // flat load over the solar gain window
+		SizingSafetyFactor:      1.15, // typical design-load margin
+		EmissionsBasis:          "average",
+		HeatingCOP:              3.0, // air-source heat pump, seasonal average
+		CoincidenceFactor:       1.0,
+		RoofAbsorptance:         0.70, // typical dark membrane
+		RoofAbsorptanceProposed: 0.37, // CA Title 24 2022 aged cool roof
+		RoofUFactor:             0.19, // CA Title 24 2022 U-0.034
+		OutputDir:               "results",
+		CSVDelimiter:            ",",
+		Color:                   "auto",
+	}
+}
+
+// configFields lists every Config JSON key in declaration order, paired
+// with the flag that overrides it.
+var configFields = []struct {
+	Key  string
+	Flag string
+}{
+	{"name", "name"},
+	{"location", "location"},
+	{"output_dir", "output"},
+	{"gzip", "gzip"},
+	{"dump_intermediates", "dump-intermediates"},
+	{"csv_delimiter", "csv-delimiter"},
+	{"csv_crlf", "csv-crlf"},
+	{"csv_columns", "columns"},
+	{"json_compact", "json-compact"},
+	{"json_with_units", "json-with-units"},
+	{"output_timestamp", "output-timestamp"},
+	{"co2_unit", "co2-unit"},
+	{"color", "color"},
+	{"sig_figs", "sig-figs"},
+	{"model", "model"},
+	{"solar_reduction", "reduction"},
+	{"electricity_cost", "cost"},
+	{"ac_cop", "cop"},
+	{"system_losses", "system-losses"},
+	{"system_type", "system-type"},
+	{"load_fraction", "load-fraction"},
+	{"cop_min", "cop-min"},
+	{"cop_max", "cop-max"},
+	{"shgc", "shgc"},
+	{"wwr", "wwr"},
+	{"transmission_factor", "transmission-factor"},
+	{"time_lag_factor", "time-lag-factor"},
+	{"thermal_mass", "thermal-mass"},
+	{"medical_equip_factor", "medical-equip-factor"},
+	{"equip_schedule", "equip-schedule"},
+	{"operating_hours", "operating-hours"},
+	{"operating_days", "operating-days"},
+	{"load_shape_factor", "load-shape-factor"},
+	{"sizing_safety_factor", "sizing-safety-factor"},
+	{"latitude", "lat"},
+	{"longitude", "lng"},
+	{"window_area", "window-area"},
+	{"wall_area", "wall-area"},
+	{"wwr_tolerance", "wwr-tolerance"},
+	{"floor_area", "floor-area"},
+	{"u_factor", "u-factor"},
+	{"delta_t", "delta-t"},
+	{"eplus_csv", "eplus-csv"},
+	{"eplus_column", "eplus-column"},
+	{"state", "state"},
+	{"cache_ttl", "cache-ttl"},
+	{"roof_area", "roof-area"},
+	{"roof_absorptance", "roof-absorptance"},
+	{"roof_absorptance_proposed", "roof-absorptance-proposed"},
+	{"roof_u_factor", "roof-u-factor"},
+	{"start_date", "start-date"},
+	{"cost_hook", "cost-hook"},
+	{"annual_bill", "annual-bill"},
+	{"baseline_emissions", "baseline-emissions"},
+	{"project_cost", "project-cost"},
+	{"dr_incentive", "dr-incentive"},
+	{"coincidence_factor", "coincidence-factor"},
+	{"pv_offset_fraction", "pv-offset-fraction"},
+	{"grid_co2", "grid-co2"},
+	{"emissions_basis", "emissions-basis"},
+	{"marginal_co2", "marginal-co2"},
+	{"carbon_price", "carbon-price"},
+	{"heating_mode", "heating-mode"},
+	{"heating_cop", "heating-cop"},
+	{"heating_cost", "heating-cost"},
+	{"heating_season_fraction", "heating-season-fraction"},
+	{"calendar", "calendar"},
+	{"holidays", "holidays"},
+	{"calendar_year", "calendar-year"},
+	{"load_shape", "load-shape"},
+	{"monthly_cdd", "monthly-cdd"},
+	{"tou_periods", "tou-periods"},
+	{"shgc_existing", "shgc-existing"},
+	{"shgc_proposed", "shgc-proposed"},
+	{"film", "film"},
+	{"diff_baseline", "diff-baseline"},
+	{"code_shgc", "code-shgc"},
+}
+
+// configSources reports, for every Config JSON key, where its effective
+// value came from: "flag" when set on the command line, "env:<variable>"
+// when set from the environment (env maps flags to their variables),
+// "file:<path>" naming the config file that supplied it, else "default".
+func configSources(changed func(name string) bool, env, fileKeys map[string]string) map[string]string {
+	sources := make(map[string]string, len(configFields))
+	for _, field := range configFields {
+		switch {
+		case changed(field.Flag):
+			sources[field.Key] = "flag"
+		case env[field.Flag] != "":
+			sources[field.Key] = "env:" + env[field.Flag]
+		case fileKeys[field.Key] != "":
+			sources[field.Key] = "file:" + fileKeys[field.Key]
+		default:
+			sources[field.Key] = "default"
+		}
+	}
+	return sources
+}
+
+// configValues returns the Config as a map keyed by JSON name.
+func configValues(config Config) map[string]any {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// reproduceCommand reconstructs a shell command that recomputes config.
+// Values equal to DefaultConfig are left out unless config.Strict is set,
+// in which case every assumption is spelled out. Inputs estimateInputs
+// derived are left out too, since the replay derives them again. API keys
+// are never included.
+func reproduceCommand(config Config) string {
+	values := configValues(config)
+	defaults := configValues(DefaultConfig())
+	derived := derivedInputs(config)
+
+	args := []string{"calculator"}
+	for _, field := range configFields {
+		value := values[field.Key]
+		required := field.Flag == "reduction" || field.Flag == "cost"
+		if derived[field.Key] || !config.Strict && !required && value == defaults[field.Key] {
+			continue
+		}
+		var text string
+		switch v := value.(type) {
+		case bool:
+			// pflag only accepts a value for a bool flag after "=".
+			if v {
+				args = append(args, "--"+field.Flag)
+			} else {
+				args = append(args, "--"+field.Flag+"=false")
+			}
+			continue
+		case float64:
+			text = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			text = fmt.Sprint(v)
+		}
+		args = append(args, "--"+field.Flag, shellQuote(text))
+	}
+	if config.Strict {
+		args = append(args, "--strict")
+	}
+	return strings.Join(args, " ")
+}
+
+// derivedInputs returns the keys estimateInputs filled in from other
+// inputs of config, which a command giving them as well is refused for.
+func derivedInputs(config Config) map[string]bool {
+	derived := make(map[string]bool)
+	if config.EPlusCSV != "" {
+		derived["solar_reduction"] = true
+	}
+	if config.SHGCExisting > 0 && config.SHGCProposed > 0 {
+		derived["solar_reduction"] = true
+	}
+	if film, ok := lookupFilm(config.Film); ok {
+		derived["shgc_proposed"] = true
+		// A project cost given with the film is an input; one equal to
+		// the film's would be derived again.
+		if config.WindowArea > 0 && config.ProjectCost == film.InstalledCost(config.WindowArea) {
+			derived["project_cost"] = true
+		}
+	}
+	return derived
+}
+
+// shellQuote wraps s in single quotes unless it consists only of
+// characters that are safe unquoted in a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+.,/:=@") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// strictFlags are the modelling assumptions --strict refuses to default.
+var strictFlags = []string{
+	"cop", "shgc", "wwr",
+	"transmission-factor", "time-lag-factor", "medical-equip-factor",
+}
+
+// unaffirmedAssumptions lists the strictFlags whose values still come from
+// DefaultConfig, given the sources reported by configSources. The time-lag
+// factor is not needed when config's thermal mass replaces it.
+func unaffirmedAssumptions(config Config, sources map[string]string) []string {
+	var missing []string
+	for _, name := range strictFlags {
+		if name == "time-lag-factor" && config.ThermalMass != "" {
+			continue
+		}
+		for _, field := range configFields {
+			if field.Flag == name && sources[field.Key] == "default" {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return missing
+}
+
+// inputHash returns a SHA-256 over the canonical JSON form of the inputs
+// that affect the calculation. Struct fields marshal in declaration order,
+// so identical inputs always hash identically. The output settings are
+// cleared since they only control how files are written, and the input
+// files are hashed by their contents rather than their paths.
+func inputHash(config Config) string {
+	config.ScenarioName = ""
+	config.OutputDir = ""
+	config.Gzip = false
+	config.DumpIntermediates = false
+	config.CSVDelimiter = ""
+	config.CSVCRLF = false
+	config.CSVColumns = ""
+	config.SigFigs = 0
+	config.CO2Unit = ""
+	config.JSONCompact = false
+	config.JSONWithUnits = false
+	config.Color = ""
+	config.OutputTimestamp = ""
+	// The COP bounds only decide which inputs are accepted, and the WWR
+	// tolerance only which are warned about.
+	config.COPMin = 0
+	config.COPMax = 0
+	config.WWRTolerance = 0
+	// The cache TTL only decides whether a lookup is fetched again.
+	config.CacheTTL = ""
+	for _, path := range []*string{&config.LoadShape, &config.EPlusCSV, &config.MonthlyCDD, &config.Holidays} {
+		*path = fileDigest(*path)
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileDigest returns the SHA-256 of the file at path, prefixed "sha256:",
+// or path itself when it is empty or cannot be read.
+func fileDigest(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return path
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// printConfig writes the effective config and the source of each value
+// as indented JSON.
+func printConfig(w io.Writer, config Config, sources map[string]string) error {
+	dump := struct {
+		Config  Config            `json:"config"`
+		Sources map[string]string `json:"sources"`
+	}{config, sources}
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// copWarnLow and copWarnHigh bracket the COPs of typical cooling
+// equipment, from old packaged units to efficient chillers. Values outside
+// are allowed but warned about.
+const (
+	copWarnLow  = 2.0
+	copWarnHigh = 8.0
+)
+
+// Warnings returns the inputs that are valid but unusual enough to be a
+// likely mistake.
+func (c Config) Warnings() []string {
+	var warnings []string
+	if c.AC_COP > 0 && (c.AC_COP < copWarnLow || c.AC_COP > copWarnHigh) {
+		warnings = append(warnings, fmt.Sprintf("COP %g is outside the typical range %g to %g for cooling equipment",
+			c.AC_COP, copWarnLow, copWarnHigh))
+	}
+	if c.WallArea == 0 && c.WWR != DefaultConfig().WWR {
+		warnings = append(warnings, fmt.Sprintf(
+			"WWR %.2f does not change the result without --wall-area; the reduction already covers the glazing", c.WWR))
+	}
+	if windowArea := c.glazedArea(); c.WallArea > 0 && c.WWR > 0 && windowArea > 0 {
+		implied := c.WWR * c.WallArea
+		if gap := math.Abs(windowArea-implied) / implied; gap > c.WWRTolerance {
+			warnings = append(warnings, fmt.Sprintf(
+				"Window area %.1f m² is %.0f%% off the %.1f m² implied by WWR %.2f over %.1f m² of wall (tolerance %.0f%%)",
+				windowArea, 100*gap, implied, c.WWR, c.WallArea, 100*c.WWRTolerance))
+		}
+	}
+	if warning := massLagWarning(c); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// glazedArea is the window area in m²: the window groups' total when
+// there are groups, else WindowArea.
+func (c Config) glazedArea() float64 {
+	if len(c.WindowGroups) == 0 {
+		return c.WindowArea
+	}
+	var area float64
+	for _, g := range c.WindowGroups {
+		area += g.Area
+	}
+	return area
+}
+
+// cacheTTL parses CacheTTL, which like --since takes days, weeks or a Go
+// duration. Zero, or an empty setting, never expires a cache entry.
+func (c Config) cacheTTL() (time.Duration, error) {
+	if c.CacheTTL == "" || c.CacheTTL == "0" {
+		return 0, nil
+	}
+	ttl, err := parseSince(c.CacheTTL)
+	if err != nil {
+		return 0, fmt.Errorf("Cache TTL must be an age such as 30d, 2w or 12h, got %q", c.CacheTTL)
+	}
+	return ttl, nil
+}
+
+// printWarnings writes each of config's warnings to w.
+func printWarnings(w io.Writer, config Config) {
+	for _, warning := range config.Warnings() {
+		fmt.Fprintf(w, "Warning: %s\n", warning)
+	}
+}
+
+// Validate checks that the config describes a computable scenario. Every
+// problem is reported, joined into one error, rather than only the first.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.HeatingMode {
+		if c.SolarReduction >= 0 {
+			errs = append(errs, errors.New("Solar reduction must be negative in heating mode (added solar gain)"))
+		}
+		if c.HeatingCOP <= 0 {
+			errs = append(errs, errors.New("Heating COP must be positive"))
+		}
+		if c.HeatingCost < 0 {
+			errs = append(errs, errors.New("Heating cost cannot be negative"))
+		}
+		if c.ElectricityCost <= 0 && c.HeatingCost <= 0 && c.CostHook == "" {
+			errs = append(errs, errors.New("Heating cost or electricity cost must be a positive number"))
+		}
+		if c.RoofArea > 0 || len(c.WindowGroups) > 0 || c.PVOffsetFraction > 0 || c.UFactor > 0 {
+			errs = append(errs, errors.New("Heating mode cannot be combined with roof, window group, conduction or PV offset savings"))
+		}
+	} else {
+		if c.SolarReduction <= 0 {
+			errs = append(errs, errors.New("Solar reduction must be a positive number"))
+		}
+		if c.HeatingSeasonFraction < 0 || c.HeatingSeasonFraction > 1 {
+			errs = append(errs, errors.New("Heating season fraction must be between 0 and 1"))
+		} else if c.HeatingSeasonFraction > 0 && c.HeatingCOP <= 0 {
+			errs = append(errs, errors.New("Heating COP must be positive to price the heating penalty"))
+		}
+		if c.ElectricityCost <= 0 && c.CostHook == "" {
+			errs = append(errs, errors.New("Electricity cost must be a positive number; give --cost, --state or a known --location"))
+		}
+	}
+	if c.SHGC <= 0 || c.SHGC > 1 {
+		errs = append(errs, errors.New("SHGC must be between 0 and 1"))
+	}
+	if c.SHGCExisting < 0 || c.SHGCExisting > 1 || c.SHGCProposed < 0 || c.SHGCProposed > 1 {
+		errs = append(errs, errors.New("Existing and proposed SHGC must be between 0 and 1"))
+	}
+	if c.EquipSchedule != "" {
+		if err := checkEquipSchedule(c.EquipSchedule); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.TOUPeriods != "" {
+		if _, err := touRates(c.TOUPeriods, c.ElectricityCost); err != nil {
+			errs = append(errs, err)
+		}
+		if c.LoadShape == "" {
+			errs = append(errs, errors.New("TOU periods require a load shape (--load-shape)"))
+		}
+		if c.CostHook != "" {
+			errs = append(errs, errors.New("TOU periods cannot be combined with a cost hook"))
+		}
+	}
+	if c.UFactor < 0 || c.UFactor > 6 {
+		errs = append(errs, errors.New("U-factor improvement must be between 0 and 6 W/m²K"))
+	}
+	if c.DeltaT < 0 || c.DeltaT > 30 {
+		errs = append(errs, errors.New("Temperature difference must be between 0 and 30 K"))
+	}
+	if c.UFactor > 0 && (c.DeltaT == 0 || c.WindowArea <= 0) {
+		errs = append(errs, errors.New("U-factor conduction needs --delta-t and --window-area"))
+	}
+	if _, ok := models[c.Model]; !ok {
+		errs = append(errs, fmt.Errorf("Model must be one of %s, got %q", strings.Join(modelNames(), ", "), c.Model))
+	}
+	if c.WallArea < 0 {
+		errs = append(errs, errors.New("Wall area cannot be negative"))
+	}
+	if c.WWRTolerance <= 0 {
+		errs = append(errs, errors.New("WWR tolerance must be positive"))
+	}
+	if c.FloorArea < 0 {
+		errs = append(errs, errors.New("Floor area cannot be negative"))
+	}
+	if _, err := c.cacheTTL(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.AnnualBill < 0 {
+		errs = append(errs, errors.New("Annual bill cannot be negative"))
+	}
+	if c.BaselineEmissions < 0 {
+		errs = append(errs, errors.New("Baseline emissions cannot be negative"))
+	}
+	if c.ProjectCost < 0 {
+		errs = append(errs, errors.New("Project cost cannot be negative"))
+	}
+	if c.CodeSHGC < 0 || c.CodeSHGC > 1 {
+		errs = append(errs, errors.New("Code SHGC must be between 0 and 1"))
+	}
+	if c.DiffBaseline && (c.SHGCExisting <= 0 || c.CostHook != "") {
+		errs = append(errs, errors.New("Diff baseline requires --shgc-existing and --shgc-proposed and no cost hook"))
+	}
+	if c.WWR <= 0 || c.WWR > 1 {
+		errs = append(errs, errors.New("WWR must be between 0 and 1"))
+	}
+	if c.AC_COP <= 0 {
+		errs = append(errs, errors.New("COP must be positive"))
+	} else if c.COPMin > 0 && c.COPMax > c.COPMin && (c.AC_COP < c.COPMin || c.AC_COP > c.COPMax) {
+		errs = append(errs, fmt.Errorf("COP %g is outside the plausible range %g to %g; check for a typo or adjust --cop-min/--cop-max",
+			c.AC_COP, c.COPMin, c.COPMax))
+	}
+	if c.COPMin <= 0 || c.COPMax <= c.COPMin {
+		errs = append(errs, errors.New("COP bounds must be positive with --cop-min below --cop-max"))
+	}
+	if _, ok := lookupMassClass(c.ThermalMass); c.ThermalMass != "" && !ok {
+		errs = append(errs, fmt.Errorf("Thermal mass must be one of %s, got %q",
+			strings.Join(massClassNames(), ", "), c.ThermalMass))
+	}
+	if c.TransmissionFactor <= 0 || c.TimeLagFactor <= 0 || c.MedicalEquipFactor <= 0 {
+		errs = append(errs, errors.New("Transmission, time lag and medical equipment factors must be positive"))
+	}
+	if c.OperatingHours <= 0 || c.OperatingHours > 24 {
+		errs = append(errs, errors.New("Operating hours must be between 0 and 24"))
+	}
+	if c.OperatingDays <= 0 || c.OperatingDays > 7 {
+		errs = append(errs, errors.New("Operating days must be between 0 and 7"))
+	}
+	if c.LoadShapeFactor < 1 {
+		errs = append(errs, errors.New("Load shape factor must be at least 1 (peak cannot be below the mean)"))
+	}
+	if c.OutputTimestamp != "" {
+		if _, err := time.Parse(time.RFC3339, c.OutputTimestamp); err != nil {
+			errs = append(errs, fmt.Errorf("Output timestamp must be RFC 3339, e.g. 2024-01-02T15:04:05Z, got %q", c.OutputTimestamp))
+		}
+	}
+	if _, ok := lookupCO2Unit(c.CO2Unit); !ok {
+		errs = append(errs, fmt.Errorf("CO2 unit must be kg, tonne or lb, got %q", c.CO2Unit))
+	}
+	if c.DRIncentive < 0 {
+		errs = append(errs, errors.New("Demand response incentive cannot be negative"))
+	}
+	if c.CoincidenceFactor < 0 || c.CoincidenceFactor > 1 {
+		errs = append(errs, errors.New("Coincidence factor must be between 0 and 1"))
+	}
+	if c.SystemLosses < 0 || c.SystemLosses > 2 {
+		errs = append(errs, errors.New("System losses must be between 0 and 2 (a fraction of compressor power)"))
+	}
+	if _, ok := systemTypes[c.SystemType]; !ok {
+		errs = append(errs, fmt.Errorf("Unknown system type %q, expected dx or chilled-water", c.SystemType))
+	}
+	if c.LoadFraction < 0.1 || c.LoadFraction > 1 {
+		errs = append(errs, errors.New("Load fraction must be between 0.1 and 1"))
+	}
+	if !slices.Contains(colorModes, c.Color) {
+		errs = append(errs, fmt.Errorf("Unknown color mode %q, expected auto, always or never", c.Color))
+	}
+	if c.SigFigs < 0 || c.SigFigs > 15 {
+		errs = append(errs, fmt.Errorf("Significant figures must be between 1 and 15, or 0 for fixed decimals, got %d", c.SigFigs))
+	}
+	if _, err := outputFormatOf(c); err != nil {
+		errs = append(errs, err)
+	}
+	if c.CalendarYear < 0 {
+		errs = append(errs, errors.New("Calendar year cannot be negative"))
+	}
+	if c.SizingSafetyFactor < 1 {
+		errs = append(errs, errors.New("Sizing safety factor must be at least 1"))
+	}
+	if c.GridCO2 < 0 {
+		errs = append(errs, errors.New("Grid CO2 intensity cannot be negative"))
+	}
+	if c.MarginalCO2 < 0 {
+		errs = append(errs, errors.New("Marginal CO2 intensity cannot be negative"))
+	}
+	if c.CarbonPrice < 0 {
+		errs = append(errs, errors.New("Carbon price cannot be negative"))
+	}
+	if c.EmissionsBasis != "average" && c.EmissionsBasis != "marginal" {
+		errs = append(errs, fmt.Errorf("Emissions basis must be average or marginal, got %q", c.EmissionsBasis))
+	}
+	if c.PVOffsetFraction < 0 || c.PVOffsetFraction > 1 {
+		errs = append(errs, errors.New("PV offset fraction must be between 0 and 1"))
+	}
+	if c.RoofArea < 0 {
+		errs = append(errs, errors.New("Roof area cannot be negative"))
+	}
+	if c.RoofArea > 0 {
+		if c.RoofAbsorptance < 0 || c.RoofAbsorptance > 1 ||
+			c.RoofAbsorptanceProposed < 0 || c.RoofAbsorptanceProposed > 1 {
+			errs = append(errs, errors.New("Roof absorptance must be between 0 and 1"))
+		}
+		if c.RoofUFactor <= 0 {
+			errs = append(errs, errors.New("Roof U-factor must be positive"))
+		}
+	}
+
+	if c.StartDate != "" {
+		start, err := time.Parse(time.DateOnly, c.StartDate)
+		if err != nil {
+			errs = append(errs, errors.New("Start date must be in YYYY-MM-DD format"))
+		} else if start.After(outputTime(c)) {
+			errs = append(errs, errors.New("Start date cannot be in the future"))
+		}
+	}
+	errs = append(errs, validateWindowGroups(c.WindowGroups)...)
+	errs = append(errs, validateConfidence(c.Confidence)...)
+
+	return markError(ErrInvalidConfig, errors.Join(errs...))
+}
+
+// loadConfigFile overlays the values in a YAML (or JSON) config file onto
+// config, using the same keys as --print-config. It returns the set of
+// keys the file supplied. Unknown keys are an error so that typos do not
+// silently fall back to defaults.
+func loadConfigFile(path string, config *Config) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, markError(ErrConfigParse, fmt.Errorf("failed to parse config file %s: %v", path, err))
+	}
+
+	// Round-trip through JSON so the file uses the Config JSON tags.
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, markError(ErrConfigParse, fmt.Errorf("failed to parse config file %s: %v", path, err))
+	}
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, markError(ErrConfigParse, fmt.Errorf("failed to parse config file %s: %v", path, err))
+	}
+
+	keys := make(map[string]bool, len(raw))
+	for key := range raw {
+		keys[key] = true
+	}
+	return keys, nil
+}
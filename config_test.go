@@ -0,0 +1,230 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestInputHashCoversOnlyTheCalculation(t *testing.T) {
+	base := defaultConfigWith(func(c *Config) {
+		c.SolarReduction, c.ElectricityCost = 100, 0.15
+		c.Confidence = map[string]string{"shgc": "measured", "ac_cop": "estimated", "solar_reduction": "measured"}
+	})
+	tests := []struct {
+		name    string
+		config  func(c *Config)
+		changes bool
+	}{
+		{"identical", func(c *Config) {}, false},
+		{"map built in another order", func(c *Config) {
+			c.Confidence = map[string]string{}
+			for _, key := range []string{"solar_reduction", "ac_cop", "shgc"} {
+				c.Confidence[key] = base.Confidence[key]
+			}
+		}, false},
+		{"scenario name", func(c *Config) { c.ScenarioName = "clinic" }, false},
+		{"output settings", func(c *Config) {
+			c.OutputDir, c.Gzip, c.CSVDelimiter, c.SigFigs, c.JSONCompact = "elsewhere", true, ";", 3, true
+		}, false},
+		{"timestamp", func(c *Config) { c.OutputTimestamp = "2024-01-01T00:00:00Z" }, false},
+		{"COP bounds", func(c *Config) { c.COPMin, c.COPMax = 2, 8 }, false},
+		{"cache TTL", func(c *Config) { c.CacheTTL = "1d" }, false},
+		{"solar reduction", func(c *Config) { c.SolarReduction = 101 }, true},
+		{"COP", func(c *Config) { c.AC_COP = 3.5 }, true},
+		{"location", func(c *Config) { c.Location = "Phoenix" }, true},
+		{"confidence", func(c *Config) { c.Confidence = map[string]string{"shgc": "measured"} }, true},
+	}
+	want := inputHash(base)
+	if len(want) != 64 {
+		t.Fatalf("inputHash = %q, want 64 hex digits", want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base
+			tt.config(&config)
+			if got := inputHash(config); (got != want) != tt.changes {
+				t.Errorf("inputHash = %s, base %s; want a different hash: %v", got, want, tt.changes)
+			}
+		})
+	}
+}
+
+func TestInputHashCoversTheInputFilesContents(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	original := write("original.txt", "2024-12-25\n")
+	same := write("copy.txt", "2024-12-25\n")
+	edited := write("edited.txt", "2024-12-25\n2024-12-26\n")
+
+	for _, key := range []string{"load_shape", "eplus_csv", "monthly_cdd", "holidays"} {
+		t.Run(key, func(t *testing.T) {
+			hash := func(path string) string {
+				config := defaultConfigWith(func(c *Config) { c.SolarReduction, c.ElectricityCost = 100, 0.15 })
+				field, err := configField(&config, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				field.SetString(path)
+				return inputHash(config)
+			}
+			want := hash(original)
+			if got := hash(same); got != want {
+				t.Errorf("a copy under another path hashed %s, want %s", got, want)
+			}
+			if got := hash(edited); got == want {
+				t.Error("a file with other contents hashed the same")
+			}
+			if err := os.WriteFile(original, []byte("2024-07-04\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := hash(original); got == want {
+				t.Error("editing the file in place left the hash unchanged")
+			}
+			if err := os.WriteFile(original, []byte("2024-12-25\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// shellFields splits a command as a POSIX shell would for the quoting
+// shellQuote produces.
+func shellFields(command string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted, inField := false, false
+	for _, r := range command {
+		switch {
+		case r == '\'':
+			quoted, inField = !quoted, true
+		case r == ' ' && !quoted:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			inField = false
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// parseCalcFlags parses args with the calc command's config and output
+// flags, as runCalc does before estimating.
+func parseCalcFlags(t *testing.T, args []string) Config {
+	t.Helper()
+	config := DefaultConfig()
+	fs := pflag.NewFlagSet("calc", pflag.ContinueOnError)
+	flags := bindConfigFlags(fs, &config)
+	bindOutputFlags(fs, &config)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	flags.record()
+	if _, err := flags.load(); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestReproduceCommandReplays(t *testing.T) {
+	solar := func() (SolarResource, error) { return SolarResource{AnnualGHI: 5.1, Source: "test"}, nil }
+	eplus := filepath.Join(t.TempDir(), "eplusout.csv")
+	if err := os.WriteFile(eplus, []byte("Date/Time,Window Gain [kWh]\n 01/01  13:00:00,90\n 01/02  13:00:00,110\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		config func(c *Config)
+		omits  string // a derived flag the command leaves out
+	}{
+		{"reduction", func(c *Config) { c.SolarReduction = 100 }, ""},
+		{"estimated from the window area", func(c *Config) { c.WindowArea = 20 }, ""},
+		{"retrofit SHGCs", func(c *Config) { c.SHGCExisting, c.SHGCProposed, c.WindowArea = 0.6, 0.3, 20 }, "--reduction"},
+		{"retrofit SHGCs under --strict", func(c *Config) {
+			c.SHGCExisting, c.SHGCProposed, c.WindowArea, c.Strict = 0.6, 0.3, 20, true
+		}, "--reduction"},
+		{"film", func(c *Config) { c.Film, c.SHGCExisting, c.WindowArea = "3M-NV35", 0.6, 20 }, "--project-cost"},
+		{"film with a quoted cost", func(c *Config) {
+			c.Film, c.SHGCExisting, c.WindowArea, c.ProjectCost = "3M-NV35", 0.6, 20, 1500
+		}, "--shgc-proposed"},
+		{"EnergyPlus CSV", func(c *Config) { c.EPlusCSV, c.EPlusColumn = eplus, "Window Gain" }, "--reduction"},
+		{"EnergyPlus CSV in heating mode", func(c *Config) {
+			c.EPlusCSV, c.EPlusColumn, c.HeatingMode = eplus, "Window Gain", true
+		}, "--reduction"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultConfigWith(func(c *Config) {
+				c.ElectricityCost = 0.15
+				tt.config(c)
+			})
+			if err := estimateInputs(&config, nil, solar, io.Discard); err != nil {
+				t.Fatal(err)
+			}
+			command := reproduceCommand(config)
+			args := shellFields(command)
+			if len(args) == 0 || args[0] != "calculator" {
+				t.Fatalf("command %q does not start with calculator", command)
+			}
+			if tt.omits != "" && slices.Contains(args, tt.omits) {
+				t.Errorf("command %q gives the derived %s", command, tt.omits)
+			}
+			replay := parseCalcFlags(t, args[1:])
+			if err := estimateInputs(&replay, nil, solar, io.Discard); err != nil {
+				t.Fatalf("replaying %s: %v", command, err)
+			}
+			want, err := computeResult(config, solar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := computeResult(replay, solar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AnnualCostSaved != want.AnnualCostSaved || replay.SolarReduction != config.SolarReduction {
+				t.Errorf("replaying %s saved $%g from %g kWh/day, want $%g from %g",
+					command, got.AnnualCostSaved, replay.SolarReduction, want.AnnualCostSaved, config.SolarReduction)
+			}
+			if replay.ProjectCost != config.ProjectCost {
+				t.Errorf("replaying %s cost $%g, want $%g", command, replay.ProjectCost, config.ProjectCost)
+			}
+		})
+	}
+}
+
+func TestConfigFieldsFollowTheDeclarationOrder(t *testing.T) {
+	var keys []string
+	config := reflect.TypeOf(Config{})
+	for i := range config.NumField() {
+		key, _, _ := strings.Cut(config.Field(i).Tag.Get("json"), ",")
+		if key != "-" && key != "window_groups" && key != "confidence" {
+			keys = append(keys, key)
+		}
+	}
+	var listed []string
+	for _, field := range configFields {
+		listed = append(listed, field.Key)
+	}
+	if !slices.Equal(listed, keys) {
+		t.Errorf("configFields keys\n%v\nwant the Config declaration order\n%v", listed, keys)
+	}
+}
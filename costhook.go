@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// costHookTimeout bounds how long an external cost model may run.
+const costHookTimeout = 10 * time.Second
+
+// CostHookInput is the JSON document written to a cost hook's stdin.
+type CostHookInput struct {
+	Location                string  `json:"location"`
+	ElectricitySavedDaily   float64 `json:"electricity_saved_kwh_day"`
+	ElectricitySavedAnnual  float64 `json:"electricity_saved_kwh_year"`
+	PeakElectricityReduced  float64 `json:"peak_electricity_reduced_kw"`
+	OperatingHours          float64 `json:"operating_hours_per_day"`
+	OperatingDays           float64 `json:"operating_days_per_week"`
+	ElectricityCostFallback float64 `json:"electricity_cost_per_kwh,omitempty"`
+	PVOffsetFraction        float64 `json:"pv_offset_fraction,omitempty"`
+}
+
+// runCostHook prices the savings with an external program. The program
+// receives a CostHookInput on stdin and must print the annual dollar
+// savings as a single number on stdout. It runs with a minimal
+// environment, from the temp directory, and is killed after
+// costHookTimeout. A relative path names a program in the working
+// directory, not the temp directory, so it is made absolute first.
+func runCostHook(path string, input CostHookInput) (float64, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode cost hook input: %v", err)
+	}
+	program, err := resolveCostHook(path)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), costHookTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, program)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Dir = os.TempDir()
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return 0, fmt.Errorf("cost hook %s timed out after %s", path, costHookTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return 0, fmt.Errorf("cost hook %s failed: %v: %s", path, err, msg)
+		}
+		return 0, fmt.Errorf("cost hook %s failed: %v", path, err)
+	}
+
+	text := strings.TrimSpace(stdout.String())
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("cost hook %s returned %q, expected a dollar amount", path, text)
+	}
+	return value, nil
+}
+
+// resolveCostHook returns the absolute path of the cost hook program: a
+// path as given, relative to the working directory, or a bare name looked
+// up on PATH.
+func resolveCostHook(path string) (string, error) {
+	if !strings.ContainsRune(path, filepath.Separator) {
+		program, err := exec.LookPath(path)
+		if err != nil {
+			return "", fmt.Errorf("cost hook %s: %v", path, err)
+		}
+		path = program
+	}
+	program, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("cost hook %s: %v", path, err)
+	}
+	return program, nil
+}
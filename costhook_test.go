@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeHook writes a shell script to path printing savings.
+func writeHook(t *testing.T, path, savings string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\ncat >/dev/null\necho "+savings+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCostHookPathsResolveFromTheWorkingDirectory(t *testing.T) {
+	work, bin := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(work, "hooks"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeHook(t, filepath.Join(work, "hook.sh"), "123.45")
+	writeHook(t, filepath.Join(work, "hooks", "hook.sh"), "67.89")
+	writeHook(t, filepath.Join(bin, "price-hook"), "42")
+
+	// A decoy where the hook runs from, which a relative path must not reach.
+	temp := t.TempDir()
+	writeHook(t, filepath.Join(temp, "hook.sh"), "100")
+	if err := os.Mkdir(filepath.Join(temp, "hooks"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeHook(t, filepath.Join(temp, "hooks", "hook.sh"), "100")
+	t.Setenv("TMPDIR", temp)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	tests := []struct {
+		name string
+		path string
+		want float64
+	}{
+		{"dot relative", "./hook.sh", 123.45},
+		{"relative", "hooks/hook.sh", 67.89},
+		{"absolute", filepath.Join(work, "hook.sh"), 123.45},
+		{"on PATH", "price-hook", 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runCostHook(tt.path, CostHookInput{Location: "Sacramento"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("runCostHook(%q) = %g, want %g", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := runCostHook("no-such-hook", CostHookInput{}); err == nil {
+		t.Error("runCostHook of a missing program succeeded")
+	}
+}
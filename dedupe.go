@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dedupeModes are the --dedupe choices for rows sharing a --dedupe-by key.
+var dedupeModes = []string{"error", "first", "last", "average"}
+
+// dedupeRows resolves the rows whose by columns repeat an earlier row's,
+// compared ignoring case and surrounding space. mode "error" reports every
+// duplicate, "first" and "last" keep one row of each group, and "average"
+// merges a group into one row of the mean of each numeric column. Every
+// row merged or dropped is reported on out. The rows keep the order of
+// each group's first line.
+func dedupeRows(header []string, rows []batchRow, by []string, mode string, out io.Writer) ([]batchRow, error) {
+	columns := make([]int, len(by))
+	for i, name := range by {
+		columns[i] = -1
+		for j, key := range header {
+			if key == strings.TrimSpace(name) {
+				columns[i] = j
+			}
+		}
+		if columns[i] < 0 {
+			return nil, fmt.Errorf("--dedupe-by %s is not a column of the scenarios CSV", name)
+		}
+	}
+	known := false
+	for _, m := range dedupeModes {
+		known = known || m == mode
+	}
+	if !known {
+		return nil, fmt.Errorf("--dedupe must be one of %s, got %q", strings.Join(dedupeModes, ", "), mode)
+	}
+
+	var order []string
+	groups := make(map[string][]batchRow)
+	for _, row := range rows {
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			if column < len(row.Record) {
+				parts[i] = strings.ToLower(strings.TrimSpace(row.Record[column]))
+			}
+		}
+		key := strings.Join(parts, "\x00")
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	var kept []batchRow
+	var errs []error
+	for _, key := range order {
+		group := groups[key]
+		if len(group) == 1 {
+			kept = append(kept, group[0])
+			continue
+		}
+		label := fmt.Sprintf("%s %q", strings.Join(by, ","), strings.ReplaceAll(key, "\x00", ","))
+		switch mode {
+		case "error":
+			errs = append(errs, fmt.Errorf("lines %s: duplicate %s", rowLines(group), label))
+		case "first":
+			kept = append(kept, group[0])
+			fmt.Fprintf(out, "Duplicate %s: kept line %d, dropped %s\n", label, group[0].Line, rowLines(group[1:]))
+		case "last":
+			last := group[len(group)-1]
+			kept = append(kept, last)
+			fmt.Fprintf(out, "Duplicate %s: kept line %d, dropped %s\n", label, last.Line, rowLines(group[:len(group)-1]))
+		case "average":
+			merged, err := averageRows(header, group)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("lines %s: %v", rowLines(group), err))
+				continue
+			}
+			kept = append(kept, merged)
+			fmt.Fprintf(out, "Duplicate %s: averaged lines %s\n", label, rowLines(group))
+		}
+	}
+	return kept, errors.Join(errs...)
+}
+
+// averageRows merges rows into one, numbered by the first line. Numeric
+// columns take the mean of the rows that set them; any other column must
+// agree wherever it is set.
+func averageRows(header []string, rows []batchRow) (batchRow, error) {
+	merged := batchRow{Line: rows[0].Line, Record: make([]string, len(header))}
+	for i, key := range header {
+		var sum float64
+		var count int
+		numeric := true
+		text := ""
+		for _, row := range rows {
+			if i >= len(row.Record) {
+				continue
+			}
+			cell := strings.TrimSpace(row.Record[i])
+			if cell == "" {
+				continue
+			}
+			if v, err := strconv.ParseFloat(cell, 64); err == nil {
+				sum += v
+			} else {
+				numeric = false
+			}
+			if text != "" && !strings.EqualFold(text, cell) && !numeric {
+				return batchRow{}, fmt.Errorf("cannot average %s: %q and %q differ", key, text, cell)
+			}
+			if text == "" {
+				text = cell
+			}
+			count++
+		}
+		switch {
+		case count == 0:
+		case numeric:
+			merged.Record[i] = strconv.FormatFloat(sum/float64(count), 'g', -1, 64)
+		default:
+			merged.Record[i] = text
+		}
+	}
+	return merged, nil
+}
+
+// rowLines lists the line numbers of rows, e.g. "2, 7".
+func rowLines(rows []batchRow) string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = strconv.Itoa(row.Line)
+	}
+	return strings.Join(lines, ", ")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// monthsPerYear is the number of rows in a monthly degree-day file.
+const monthsPerYear = 12
+
+// readMonthlyCDD reads the cooling degree days of each month, January to
+// December, one per line. A line may hold the month before the value,
+// and a header line is skipped.
+func readMonthlyCDD(r io.Reader) ([]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var cdd []float64
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		text := strings.TrimSpace(record[len(record)-1])
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid degree days %q", line, text)
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("line %d: degree days cannot be negative", line)
+		}
+		cdd = append(cdd, value)
+	}
+	if len(cdd) != monthsPerYear {
+		return nil, fmt.Errorf("expected %d monthly values, got %d", monthsPerYear, len(cdd))
+	}
+	var total float64
+	for _, v := range cdd {
+		total += v
+	}
+	if total == 0 {
+		return nil, errors.New("no cooling degree days in any month")
+	}
+	return cdd, nil
+}
+
+// applyMonthlyCDD reads config's monthly degree-day file.
+func applyMonthlyCDD(config *Config) error {
+	if config.HeatingMode {
+		return errors.New("--monthly-cdd weights cooling savings and cannot be combined with --heating-mode")
+	}
+	file, err := os.Open(config.MonthlyCDD)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	cdd, err := readMonthlyCDD(file)
+	if err != nil {
+		return fmt.Errorf("monthly CDD %s: %v", config.MonthlyCDD, err)
+	}
+	config.MonthlyCDDValues = cdd
+	return nil
+}
+
+// MonthSavings is the share of the annual window savings falling in one
+// month.
+type MonthSavings struct {
+	Month            string  `json:"month"`
+	CDD              float64 `json:"cdd"`
+	ElectricitySaved float64 `json:"electricity_saved_kwh"`
+	CostSaved        float64 `json:"cost_saved_usd"`
+}
+
+// monthlySavings distributes the annual electricity and cost savings
+// across the months in proportion to their cooling degree days, so a
+// month without cooling saves nothing and the months add up to the year.
+func monthlySavings(result Result, cdd []float64) []MonthSavings {
+	var total float64
+	for _, v := range cdd {
+		total += v
+	}
+	annualKWh := result.ElectricitySaved * 365 * result.OperatingFactor
+	months := make([]MonthSavings, len(cdd))
+	for i, v := range cdd {
+		share := v / total
+		months[i] = MonthSavings{
+			Month:            time.Month(i + 1).String()[:3],
+			CDD:              v,
+			ElectricitySaved: annualKWh * share,
+			CostSaved:        result.AnnualCostSaved * share,
+		}
+	}
+	return months
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eiaURL is the EIA retail sales endpoint; tests point it at a local
+// server.
+var eiaURL = "https://api.eia.gov/v2/electricity/retail-sales/data/"
+
+// statePrices holds bundled average commercial electricity prices in
+// $/kWh by state, from EIA retail sales data (2023 annual, rounded).
+var statePrices = map[string]float64{
+	"AL": 0.136, "AK": 0.208, "AZ": 0.120, "AR": 0.105, "CA": 0.245,
+	"CO": 0.128, "CT": 0.220, "DE": 0.118, "DC": 0.151, "FL": 0.125,
+	"GA": 0.122, "HI": 0.390, "ID": 0.085, "IL": 0.123, "IN": 0.132,
+	"IA": 0.110, "KS": 0.118, "KY": 0.122, "LA": 0.117, "ME": 0.190,
+	"MD": 0.131, "MA": 0.218, "MI": 0.135, "MN": 0.128, "MS": 0.125,
+	"MO": 0.107, "MT": 0.118, "NE": 0.097, "NV": 0.110, "NH": 0.196,
+	"NJ": 0.152, "NM": 0.115, "NY": 0.190, "NC": 0.106, "ND": 0.097,
+	"OH": 0.113, "OK": 0.098, "OR": 0.110, "PA": 0.119, "RI": 0.214,
+	"SC": 0.118, "SD": 0.112, "TN": 0.126, "TX": 0.096, "UT": 0.097,
+	"VT": 0.200, "VA": 0.098, "WA": 0.107, "WV": 0.115, "WI": 0.128,
+	"WY": 0.104,
+}
+
+// PriceEstimate is an electricity price derived from a lookup rather than
+// supplied by the user.
+type PriceEstimate struct {
+	Price  float64 `json:"price"` // $/kWh
+	Period string  `json:"period"`
+	Source string  `json:"source"`
+
+	// CacheAge is how old the disk cache entry served was; zero for
+	// live and bundled prices.
+	CacheAge time.Duration `json:"-"`
+}
+
+type eiaResponse struct {
+	Response struct {
+		Data []struct {
+			Period string `json:"period"`
+			Price  any    `json:"price"` // cents/kWh, sent as a string
+		} `json:"data"`
+	} `json:"response"`
+	Error string `json:"error"`
+}
+
+// fetchStatePrice returns the latest annual average commercial price in
+// a state, reading the disk cache first and querying EIA on a miss or an
+// entry older than ttl. When that query fails an expired entry is still
+// returned, with a warning saying so.
+func fetchStatePrice(apiKey, state string, ttl time.Duration) (PriceEstimate, string, error) {
+	cacheName := fmt.Sprintf("eia_%s.json", state)
+	var cached PriceEstimate
+	age, found := readCache(cacheName, &cached)
+	if found {
+		cached.CacheAge = age
+		if cacheFresh(age, ttl) {
+			return cached, "", nil
+		}
+	}
+
+	estimate, err := queryStatePrice(apiKey, state)
+	if err != nil {
+		if found {
+			return cached, fmt.Sprintf("%v; using the cached price for %s, %s and past --cache-ttl",
+				err, state, cacheAge(age)), nil
+		}
+		return PriceEstimate{}, "", err
+	}
+	writeCache(cacheName, estimate)
+	return estimate, "", nil
+}
+
+// queryStatePrice asks the EIA API for the latest annual average
+// commercial price in a state.
+func queryStatePrice(apiKey, state string) (PriceEstimate, error) {
+
+	params := url.Values{}
+	params.Set("api_key", apiKey)
+	params.Set("frequency", "annual")
+	params.Set("data[0]", "price")
+	params.Set("facets[stateid][]", state)
+	params.Set("facets[sectorid][]", "COM")
+	params.Set("sort[0][column]", "period")
+	params.Set("sort[0][direction]", "desc")
+	params.Set("length", "1")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(eiaURL + "?" + params.Encode())
+	if err != nil {
+		// The request URL carries the API key, so report only the cause.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return PriceEstimate{}, fmt.Errorf("failed to query EIA: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return PriceEstimate{}, fmt.Errorf("failed to read EIA response: %v", err)
+	}
+
+	var parsed eiaResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return PriceEstimate{}, fmt.Errorf("failed to parse EIA response: %v", err)
+	}
+	if parsed.Error != "" {
+		return PriceEstimate{}, fmt.Errorf("EIA returned an error: %s", parsed.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return PriceEstimate{}, fmt.Errorf("EIA returned status %s", resp.Status)
+	}
+	if len(parsed.Response.Data) == 0 {
+		return PriceEstimate{}, fmt.Errorf("EIA returned no price data for %s", state)
+	}
+
+	row := parsed.Response.Data[0]
+	cents, err := strconv.ParseFloat(fmt.Sprint(row.Price), 64)
+	if err != nil {
+		return PriceEstimate{}, fmt.Errorf("EIA returned an invalid price %v", row.Price)
+	}
+
+	estimate := PriceEstimate{
+		Price:  cents / 100,
+		Period: row.Period,
+		Source: "EIA",
+	}
+	return estimate, nil
+}
+
+// resolveStatePrice estimates the commercial electricity price for a
+// state, preferring live EIA data and falling back to the bundled table.
+// The returned warning is non-empty when a live lookup failed.
+func resolveStatePrice(apiKey, state string, ttl time.Duration) (PriceEstimate, string, error) {
+	state = strings.ToUpper(strings.TrimSpace(state))
+	var warning string
+
+	if apiKey != "" {
+		estimate, cacheWarning, err := fetchStatePrice(apiKey, state, ttl)
+		if err == nil {
+			return estimate, cacheWarning, nil
+		}
+		warning = fmt.Sprintf("%v; using bundled price for %s", err, state)
+	}
+
+	price, ok := statePrices[state]
+	if !ok {
+		return PriceEstimate{}, warning, fmt.Errorf("no bundled electricity price for state %q", state)
+	}
+	return PriceEstimate{Price: price, Period: "2023", Source: "bundled"}, warning, nil
+}
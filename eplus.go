@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EPlusImport is the daily solar reduction summed from an EnergyPlus
+// output column.
+type EPlusImport struct {
+	Column string
+	Days   int     // distinct dates in the file
+	Total  float64 // kWh over the file
+}
+
+// Daily is the mean reduction per day of the file.
+func (e EPlusImport) Daily() float64 {
+	return e.Total / float64(e.Days)
+}
+
+// ePlusUnit returns the factor converting an EnergyPlus column's values
+// to kWh, from the "[J]" style unit in its header. Rates in W are only
+// accepted for hourly reporting, where each value is Wh.
+func ePlusUnit(header string) (float64, error) {
+	switch {
+	case strings.Contains(header, "[J]"):
+		return 1 / 3.6e6, nil
+	case strings.Contains(header, "[kWh]"):
+		return 1, nil
+	case strings.Contains(header, "[Wh]"):
+		return 1e-3, nil
+	case strings.Contains(header, "[W]") && strings.Contains(header, "(Hourly)"):
+		return 1e-3, nil
+	}
+	return 0, fmt.Errorf("column %q: expected energy in [J], [Wh] or [kWh], or a rate in [W] reported (Hourly)", header)
+}
+
+// findEPlusColumn returns the index of the column named name exactly, or
+// else of the only column containing it, ignoring case.
+func findEPlusColumn(header []string, name string) (int, error) {
+	match := -1
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		if strings.EqualFold(h, name) {
+			return i, nil
+		}
+		if strings.Contains(strings.ToLower(h), strings.ToLower(name)) {
+			if match >= 0 {
+				return 0, fmt.Errorf("column %q matches both %q and %q; give more of the name",
+					name, strings.TrimSpace(header[match]), h)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return 0, fmt.Errorf("no column matching %q", name)
+	}
+	return match, nil
+}
+
+// readEPlusCSV sums column of an EnergyPlus eplusout.csv read from r. The
+// first column is EnergyPlus's Date/Time, " MM/DD  HH:MM:SS", whose dates
+// count the days covered. Summary rows without a value are skipped.
+func readEPlusCSV(r io.Reader, column string) (EPlusImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return EPlusImport{}, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	col, err := findEPlusColumn(header, column)
+	if err != nil {
+		return EPlusImport{}, err
+	}
+	toKWh, err := ePlusUnit(header[col])
+	if err != nil {
+		return EPlusImport{}, err
+	}
+
+	result := EPlusImport{Column: strings.TrimSpace(header[col])}
+	days := make(map[string]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return EPlusImport{}, fmt.Errorf("line %d: %v", line, err)
+		}
+		if col >= len(record) || strings.TrimSpace(record[col]) == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[col]), 64)
+		if err != nil {
+			return EPlusImport{}, fmt.Errorf("line %d: invalid number %q", line, record[col])
+		}
+		date, _, _ := strings.Cut(strings.TrimSpace(record[0]), " ")
+		days[date] = true
+		result.Total += value * toKWh
+	}
+	if len(days) == 0 {
+		return EPlusImport{}, errors.New("no values in the column")
+	}
+	result.Days = len(days)
+	return result, nil
+}
+
+// importEPlus reads config's EnergyPlus file and column.
+func importEPlus(config Config) (EPlusImport, error) {
+	if config.EPlusColumn == "" {
+		return EPlusImport{}, errors.New("--eplus-csv requires --eplus-column")
+	}
+	file, err := os.Open(config.EPlusCSV)
+	if err != nil {
+		return EPlusImport{}, err
+	}
+	defer file.Close()
+	imported, err := readEPlusCSV(file, config.EPlusColumn)
+	if err != nil {
+		return EPlusImport{}, fmt.Errorf("%s: %v", config.EPlusCSV, err)
+	}
+	return imported, nil
+}
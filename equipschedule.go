@@ -0,0 +1,53 @@
+package main
+
+import "fmt"
+
+// solarDayStart and solarDayEnd bound the half hours over which an
+// equipment schedule is averaged when there is no load shape: the
+// daytime hours in which the solar cooling load arises.
+const (
+	solarDayStart = 12 // 06:00
+	solarDayEnd   = 36 // 18:00
+)
+
+// equipFactor is the medical equipment factor the cooling calculation
+// uses. Without an equipment schedule it is the scalar factor. With one,
+// the half-hourly factors are weighted by when the solar cooling load
+// occurs: by the load shape when there is one, and otherwise evenly over
+// the daytime hours.
+func equipFactor(config Config) float64 {
+	if config.EquipSchedule == "" {
+		return config.MedicalEquipFactor
+	}
+	factors, err := halfHourly(config.EquipSchedule, config.MedicalEquipFactor)
+	if err != nil {
+		// Validate has checked the schedule.
+		return config.MedicalEquipFactor
+	}
+	if len(config.LoadShapeValues) == halfHours {
+		var weighted float64
+		for i, fraction := range config.LoadShapeValues {
+			weighted += fraction * factors[i]
+		}
+		return weighted
+	}
+	var sum float64
+	for i := solarDayStart; i < solarDayEnd; i++ {
+		sum += factors[i]
+	}
+	return sum / (solarDayEnd - solarDayStart)
+}
+
+// checkEquipSchedule reports a malformed --equip-schedule.
+func checkEquipSchedule(schedule string) error {
+	factors, err := halfHourly(schedule, 1)
+	if err != nil {
+		return fmt.Errorf("Equipment schedule: %v", err)
+	}
+	for _, f := range factors {
+		if f <= 0 {
+			return fmt.Errorf("Equipment schedule factors must be positive")
+		}
+	}
+	return nil
+}
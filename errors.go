@@ -0,0 +1,40 @@
+package main
+
+import "errors"
+
+// Errors returned by the calculator are marked with one of these kinds,
+// so that callers can tell them apart with errors.Is while the message
+// stays the one printed to the user.
+var (
+	// ErrInvalidConfig marks the errors from Config.Validate: an input
+	// out of range or a combination of inputs that cannot be modelled.
+	// Every problem found is joined into the one error.
+	ErrInvalidConfig = errors.New("invalid configuration")
+
+	// ErrConfigParse marks a config file, or a serve request body, that
+	// is not valid YAML or JSON, has a value of the wrong type, or names
+	// an unknown key. A config file that cannot be read is an I/O error
+	// and is not marked.
+	ErrConfigParse = errors.New("invalid config file")
+
+	// ErrWrite marks a failure to create or write the JSON and CSV
+	// result files, including the output directory.
+	ErrWrite = errors.New("failed to write results")
+)
+
+// kindError is err marked as one of the kinds above. It unwraps to both,
+// so errors.Is also still finds causes such as fs.ErrPermission.
+type kindError struct {
+	kind, err error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// markError marks err as kind, returning nil for a nil err.
+func markError(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind, err}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// sqFtPerM2 converts the film prices quoted per ft² to the m² of
+// --window-area.
+const sqFtPerM2 = 10.7639
+
+// WindowFilm is a bundled window-film product.
+type WindowFilm struct {
+	Name      string
+	SHGC      float64 // on 6 mm single clear glass, as vendors quote it
+	CostPerFt float64 // $/ft² installed, typical commercial pricing
+}
+
+// InstalledCost is the film's typical installed cost over windowArea m².
+func (f WindowFilm) InstalledCost(windowArea float64) float64 {
+	return windowArea * sqFtPerM2 * f.CostPerFt
+}
+
+// films is the bundled film table. SHGCs are the manufacturers' published
+// values on single clear glass, rounded; the installed costs are typical
+// 2023 commercial quotes and vary widely by market and job size.
+var films = map[string]WindowFilm{
+	"3m-nv35":      {Name: "3M-NV35", SHGC: 0.40, CostPerFt: 8.00},
+	"3m-nv25":      {Name: "3M-NV25", SHGC: 0.33, CostPerFt: 8.50},
+	"3m-pr70":      {Name: "3M-PR70", SHGC: 0.48, CostPerFt: 12.00},
+	"3m-pr40":      {Name: "3M-PR40", SHGC: 0.36, CostPerFt: 11.00},
+	"llumar-n1020": {Name: "LLumar-N1020", SHGC: 0.26, CostPerFt: 7.00},
+	"llumar-ctx":   {Name: "LLumar-CTX", SHGC: 0.47, CostPerFt: 10.00},
+	"vkool-70":     {Name: "VKool-70", SHGC: 0.45, CostPerFt: 13.00},
+}
+
+// lookupFilm finds a bundled film by name, ignoring case and surrounding
+// whitespace.
+func lookupFilm(name string) (WindowFilm, bool) {
+	film, ok := films[strings.ToLower(strings.TrimSpace(name))]
+	return film, ok
+}
+
+// filmNames returns the bundled film names, sorted.
+func filmNames() []string {
+	names := make([]string, 0, len(films))
+	for _, film := range films {
+		names = append(names, film.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyFilm sets the proposed SHGC from config.Film and, with a window
+// area and no project cost given, the installed cost.
+func applyFilm(config *Config, out io.Writer) error {
+	film, ok := lookupFilm(config.Film)
+	if !ok {
+		return fmt.Errorf("unknown film %q, expected one of %s (see --list-films)",
+			config.Film, strings.Join(filmNames(), ", "))
+	}
+	if config.SHGCProposed > 0 {
+		return errors.New("--film sets --shgc-proposed; give one or the other")
+	}
+	if config.SHGCExisting <= 0 {
+		return errors.New("--film needs --shgc-existing for the glass it is applied to")
+	}
+	config.SHGCProposed = film.SHGC
+	if config.ProjectCost == 0 && config.WindowArea > 0 {
+		config.ProjectCost = film.InstalledCost(config.WindowArea)
+		fmt.Fprintf(out, "Film %s: SHGC %.2f, installed cost $%.0f ($%.2f/ft² over %.1f m²)\n",
+			film.Name, film.SHGC, config.ProjectCost, film.CostPerFt, config.WindowArea)
+	}
+	return nil
+}
+
+// printFilms lists the bundled films.
+func printFilms(w io.Writer) {
+	fmt.Fprintf(w, "%-14s %6s %10s\n", "Film", "SHGC", "$/ft²")
+	for _, name := range filmNames() {
+		film, _ := lookupFilm(name)
+		fmt.Fprintf(w, "%-14s %6.2f %10.2f\n", film.Name, film.SHGC, film.CostPerFt)
+	}
+}
+
+// filmComparison is one film's result in a --compare-film table.
+type filmComparison struct {
+	Film   WindowFilm
+	Result Result
+	Config Config
+}
+
+// compareFilms calculates config with each of the named films, ranked by
+// payback and then by savings.
+func compareFilms(config Config, names []string, sources map[string]string) ([]filmComparison, error) {
+	rows := make([]filmComparison, 0, len(names))
+	for _, name := range names {
+		scenario := config
+		scenario.Film = name
+		result, scenario, err := runScenario(scenario, sources, io.Discard)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		film, _ := lookupFilm(name)
+		rows = append(rows, filmComparison{film, result, scenario})
+	}
+	payback := func(r filmComparison) float64 {
+		if r.Result.PaybackYears == 0 {
+			return math.Inf(1)
+		}
+		return r.Result.PaybackYears
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if payback(rows[i]) != payback(rows[j]) {
+			return payback(rows[i]) < payback(rows[j])
+		}
+		return rows[i].Result.NetAnnualSavings > rows[j].Result.NetAnnualSavings
+	})
+	return rows, nil
+}
+
+// printFilmComparison tabulates the ranked films.
+func printFilmComparison(w io.Writer, rows []filmComparison) {
+	fmt.Fprintf(w, "%-4s %-14s %6s %12s %12s %10s\n", "Rank", "Film", "SHGC", "Savings $/yr", "Cost $", "Payback yr")
+	for i, r := range rows {
+		payback := "never"
+		if p := r.Result.PaybackYears; p > 0 && !math.IsInf(p, 1) {
+			payback = fmt.Sprintf("%.1f", p)
+		} else if p == 0 {
+			payback = "-"
+		}
+		fmt.Fprintf(w, "%-4d %-14s %6.2f %12.2f %12.0f %10s\n",
+			i+1, r.Film.Name, r.Film.SHGC, r.Result.NetAnnualSavings, r.Config.ProjectCost, payback)
+	}
+}
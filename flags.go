@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// configFlags binds the Config fields to a command's flag set and layers
+// the sources in order: defaults, the --config file, SOLARCALC_
+// environment variables, then flags.
+type configFlags struct {
+	flags  *pflag.FlagSet
+	config *Config
+	paths  []string
+	given  [][2]string
+	// env maps each flag set from the environment to its variable.
+	env map[string]string
+}
+
+// envPrefix starts the environment variable for every flag: --cop is
+// SOLARCALC_COP and --time-lag-factor SOLARCALC_TIME_LAG_FACTOR.
+const envPrefix = "SOLARCALC_"
+
+// envVar is the environment variable that sets the flag name.
+func envVar(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// bindConfigFlags registers the model inputs, --config and --strict on fs,
+// writing parsed values into config.
+func bindConfigFlags(fs *pflag.FlagSet, config *Config) *configFlags {
+	unitFloatVarP(fs, &config.SolarReduction, "reduction", "r", 0.0, unitKWhPerDay,
+		"Total solar radiation reduction in kWh/day")
+	unitFloatVarP(fs, &config.ElectricityCost, "cost", "c", 0.0, unitUSDPerKWh,
+		"Electricity cost in $/kWh")
+
+	fs.StringVar(&config.ScenarioName, "name", config.ScenarioName,
+		"Label for this scenario in the report, JSON and CSV; a name column does the same in batch")
+	fs.StringVar(&config.Model, "model", config.Model,
+		"Calculation model: "+strings.Join(modelNames(), ", ")+"; a custom build may register more")
+	fs.StringVarP(&config.Location, "location", "l", config.Location,
+		"Building location; drives the default grid CO2 rate and fallback irradiance")
+	fs.Float64Var(&config.AC_COP, "cop", config.AC_COP,
+		"Air conditioning Coefficient of Performance")
+	fs.Float64Var(&config.SystemLosses, "system-losses", config.SystemLosses,
+		"Fan and pump power as a fraction of compressor power, e.g. 0.25; counts whole-system electricity")
+	fs.StringVar(&config.SystemType, "system-type", config.SystemType,
+		"Cooling plant, dx or chilled-water, whose part-load curve adjusts --cop at --load-fraction")
+	fs.Float64Var(&config.LoadFraction, "load-fraction", config.LoadFraction,
+		"Typical part-load ratio of the cooling plant, 0.1 to 1; 1 uses --cop as rated")
+	fs.Float64Var(&config.COPMin, "cop-min", config.COPMin,
+		"Lowest --cop accepted; smaller values are rejected as typos")
+	fs.Float64Var(&config.COPMax, "cop-max", config.COPMax,
+		"Highest --cop accepted; larger values are rejected as typos")
+	fs.Float64Var(&config.SHGC, "shgc", config.SHGC,
+		"Solar Heat Gain Coefficient")
+	fs.Float64Var(&config.SHGCExisting, "shgc-existing", config.SHGCExisting,
+		"SHGC of the existing glazing; with --shgc-proposed and --window-area, replaces --reduction")
+	fs.StringVar(&config.Film, "film", config.Film,
+		"Bundled window film, e.g. 3M-NV35: sets --shgc-proposed and, with --window-area, the --project-cost")
+	fs.Float64Var(&config.SHGCProposed, "shgc-proposed", config.SHGCProposed,
+		"SHGC of the glazing after the retrofit")
+	fs.BoolVar(&config.DiffBaseline, "diff-baseline", config.DiffBaseline,
+		"Report the savings beyond a code-minimum retrofit of the same windows")
+	fs.Float64Var(&config.CodeSHGC, "code-shgc", config.CodeSHGC,
+		"Code-minimum SHGC for --diff-baseline (default: from --location)")
+	fs.Float64Var(&config.WWR, "wwr", config.WWR,
+		"Window to Wall Ratio; sets the window area over --wall-area, else it is informational")
+	fs.Float64Var(&config.TransmissionFactor, "transmission-factor", config.TransmissionFactor,
+		"Fraction of blocked radiation that would have entered as heat")
+	fs.Float64Var(&config.TimeLagFactor, "time-lag-factor", config.TimeLagFactor,
+		"Thermal mass time-lag factor")
+	fs.StringVar(&config.ThermalMass, "thermal-mass", config.ThermalMass,
+		"Building mass class light, medium or heavy; models lag and peak damping instead of --time-lag-factor")
+	fs.Float64Var(&config.MedicalEquipFactor, "medical-equip-factor", config.MedicalEquipFactor,
+		"Cooling load multiplier for medical equipment heat gain")
+	fs.StringVar(&config.EquipSchedule, "equip-schedule", config.EquipSchedule,
+		"Equipment factor by time of day, e.g. 08:00-17:00=1.6; --medical-equip-factor at other times")
+	fs.Float64Var(&config.LoadShapeFactor, "load-shape-factor", config.LoadShapeFactor,
+		"Peak-to-mean ratio of the solar cooling load, used to derive peak kW")
+	fs.StringVar(&config.LoadShape, "load-shape", config.LoadShape,
+		"CSV of 48 half-hourly fractions of the daily cooling load; replaces --load-shape-factor")
+	fs.StringVar(&config.MonthlyCDD, "monthly-cdd", config.MonthlyCDD,
+		"CSV of 12 monthly cooling degree days, January first, to split the annual savings by month")
+	fs.StringVar(&config.TOUPeriods, "tou-periods", config.TOUPeriods,
+		"Time-of-use rates over the load shape, e.g. 16:00-21:00=0.35; other hours at --cost")
+	fs.Float64Var(&config.SizingSafetyFactor, "sizing-safety-factor", config.SizingSafetyFactor,
+		"Design margin applied to the peak reduction for the advisory equipment sizing")
+	fs.Float64Var(&config.OperatingHours, "operating-hours", config.OperatingHours,
+		"Hours per day the building is conditioned")
+	fs.Float64Var(&config.OperatingDays, "operating-days", config.OperatingDays,
+		"Days per week the building is conditioned")
+	unitFloatVar(fs, &config.WindowArea, "window-area", config.WindowArea, unitM2,
+		"Glazed area in m², used to estimate --reduction from irradiance")
+	unitFloatVar(fs, &config.WallArea, "wall-area", config.WallArea, unitM2,
+		"Gross exterior wall area in m²; without --window-area the window area is --wwr times it")
+	fs.Float64Var(&config.WWRTolerance, "wwr-tolerance", config.WWRTolerance,
+		"Relative gap between --window-area and --wwr x --wall-area above which to warn")
+	unitFloatVar(fs, &config.FloorArea, "floor-area", config.FloorArea, unitM2,
+		"Conditioned floor area in m², to report the savings per m² of floor")
+	unitFloatVar(fs, &config.UFactor, "u-factor", config.UFactor, unitUFactor,
+		"Window U-factor improvement in W/m²K, e.g. 1.1 from 2.8 to 1.7; adds conduction over --window-area")
+	unitFloatVar(fs, &config.DeltaT, "delta-t", config.DeltaT, unitKelvin,
+		"Mean outdoor minus indoor temperature in K during cooling, for --u-factor")
+	fs.StringVar(&config.EPlusCSV, "eplus-csv", config.EPlusCSV,
+		"EnergyPlus eplusout.csv whose --eplus-column is summed into --reduction")
+	fs.StringVar(&config.EPlusColumn, "eplus-column", config.EPlusColumn,
+		"Header, or a unique part of it, of the solar radiation column in --eplus-csv")
+	fs.Float64Var(&config.Latitude, "lat", config.Latitude,
+		"Site latitude for NREL irradiance lookup")
+	fs.Float64Var(&config.Longitude, "lng", config.Longitude,
+		"Site longitude for NREL irradiance lookup")
+	fs.StringVar(&config.NRELAPIKey, "nrel-api-key", config.NRELAPIKey,
+		"NREL developer API key for live irradiance data")
+	unitFloatVar(fs, &config.RoofArea, "roof-area", config.RoofArea, unitM2,
+		"Roof area in m² for the cool-roof component (off when 0)")
+	fs.Float64Var(&config.RoofAbsorptance, "roof-absorptance", config.RoofAbsorptance,
+		"Solar absorptance of the existing roof")
+	fs.Float64Var(&config.RoofAbsorptanceProposed, "roof-absorptance-proposed", config.RoofAbsorptanceProposed,
+		"Solar absorptance of the proposed roof")
+	unitFloatVar(fs, &config.RoofUFactor, "roof-u-factor", config.RoofUFactor, unitUFactor,
+		"Roof assembly U-factor in W/m²K")
+	fs.StringVar(&config.StartDate, "start-date", config.StartDate,
+		"Date (YYYY-MM-DD) the retrofit could have started, to report savings foregone")
+	fs.StringVar(&config.CostHook, "cost-hook", config.CostHook,
+		"Program that prices the savings: reads JSON on stdin, prints annual $ on stdout")
+	fs.Float64Var(&config.PVOffsetFraction, "pv-offset-fraction", config.PVOffsetFraction,
+		"Advanced: share of the saved cooling electricity that on-site PV was supplying")
+	fs.BoolVar(&config.HeatingMode, "heating-mode", config.HeatingMode,
+		"Price added solar gain (negative --reduction) as heating savings")
+	fs.Float64Var(&config.HeatingCOP, "heating-cop", config.HeatingCOP,
+		"Heating system COP or efficiency used in --heating-mode")
+	unitFloatVar(fs, &config.HeatingCost, "heating-cost", config.HeatingCost, unitUSDPerKWh,
+		"Heating energy cost in $/kWh for --heating-mode (default: --cost)")
+	fs.Float64Var(&config.HeatingSeasonFraction, "heating-season-fraction", config.HeatingSeasonFraction,
+		"Share of the year the blocked gain would offset heating; priced as a heating penalty, and left out of the cooling savings (default: off)")
+	fs.StringVar(&config.Calendar, "calendar", config.Calendar,
+		"Open weekdays, e.g. mon-fri; annualizes by counting the open days in the year")
+	fs.StringVar(&config.Holidays, "holidays", config.Holidays,
+		"File of closure dates (YYYY-MM-DD per line) removed from --calendar")
+	fs.IntVar(&config.CalendarYear, "calendar-year", config.CalendarYear,
+		"Year whose days --calendar counts (default: this year)")
+	unitFloatVar(fs, &config.GridCO2, "grid-co2", config.GridCO2, unitKgPerKWh,
+		"Grid emission rate in kg CO2e/kWh (default: from --location, else US average)")
+	fs.StringVar(&config.EmissionsBasis, "emissions-basis", config.EmissionsBasis,
+		"Emission rate for CO2 avoided: average (all generation) or marginal (displaced generation)")
+	unitFloatVar(fs, &config.MarginalCO2, "marginal-co2", config.MarginalCO2, unitKgPerKWh,
+		"Marginal emission rate in kg CO2e/kWh for --emissions-basis marginal (default: from --location)")
+	unitFloatVar(fs, &config.CarbonPrice, "carbon-price", config.CarbonPrice, unitUSDPerT,
+		"Internal carbon price in $/tonne CO2e, to add the value of the CO2 avoided to the annual benefit")
+	unitFloatVar(fs, &config.AnnualBill, "annual-bill", config.AnnualBill, unitUSD,
+		"Annual electricity bill in $, to report the savings as a percentage of it")
+	unitFloatVar(fs, &config.BaselineEmissions, "baseline-emissions", config.BaselineEmissions, unitKgPerYear,
+		"Building's annual emissions in kg CO2e, to report the CO2 avoided as a percentage of them")
+	unitFloatVar(fs, &config.ProjectCost, "project-cost", config.ProjectCost, unitUSD,
+		"Installed cost of the retrofit in $, to report the simple payback")
+	fs.Float64Var(&config.DRIncentive, "dr-incentive", config.DRIncentive,
+		"Demand response incentive in $/kW-year, reported as revenue separate from the savings")
+	fs.Float64Var(&config.CoincidenceFactor, "coincidence-factor", config.CoincidenceFactor,
+		"Share of the peak demand reduction that coincides with the utility peak, for --dr-incentive")
+	fs.StringVar(&config.State, "state", config.State,
+		"Two-letter US state used to estimate --cost when omitted")
+	fs.StringVar(&config.EIAAPIKey, "eia-api-key", config.EIAAPIKey,
+		"EIA API key for live electricity prices")
+	fs.StringVar(&config.CacheTTL, "cache-ttl", config.CacheTTL,
+		"Refetch cached NREL and EIA data older than this, e.g. 30d, 2w or 12h; 0 keeps it forever")
+
+	c := &configFlags{flags: fs, config: config}
+	fs.StringArrayVar(&c.paths, "config", nil,
+		"YAML or JSON config file; repeat to layer files, later ones and then flags override")
+	fs.BoolVar(&config.Strict, "strict", false,
+		"Require every modelling assumption to be set explicitly")
+	return c
+}
+
+// bindOutputFlags registers the flags controlling where results are written.
+func bindOutputFlags(fs *pflag.FlagSet, config *Config) {
+	fs.StringVarP(&config.OutputDir, "output", "o", config.OutputDir,
+		"Output directory for CSV and JSON files")
+	fs.BoolVar(&config.Gzip, "gzip", config.Gzip,
+		"Write gzip-compressed .json.gz and .csv.gz files")
+	fs.BoolVar(&config.DumpIntermediates, "dump-intermediates", config.DumpIntermediates,
+		"Add every intermediate quantity of the calculation to the JSON output")
+	fs.StringVar(&config.CSVDelimiter, "csv-delimiter", config.CSVDelimiter,
+		"Field delimiter for CSV files, e.g. ';' for European Excel")
+	fs.BoolVar(&config.CSVCRLF, "csv-crlf", config.CSVCRLF,
+		"End CSV lines with CRLF instead of LF")
+	fs.StringVar(&config.CSVColumns, "columns", config.CSVColumns,
+		"Comma-separated CSV columns to write, in order, e.g. location,annual_cost_saved,co2_avoided")
+	fs.StringVar(&config.OutputTimestamp, "output-timestamp", config.OutputTimestamp,
+		"RFC 3339 time to stamp results and file names with instead of now, for reproducible output")
+	fs.BoolVar(&config.JSONCompact, "json-compact", config.JSONCompact,
+		"Write minified JSON files instead of indented ones")
+	fs.BoolVar(&config.JSONWithUnits, "json-with-units", config.JSONWithUnits,
+		"Write each numeric result in the JSON files as {\"value\", \"units\"} instead of a bare number")
+	fs.StringVar(&config.CO2Unit, "co2-unit", config.CO2Unit,
+		"Unit of the CO2 avoided in the report and tables: kg, tonne or lb; JSON stays in kg")
+	fs.StringVar(&config.Color, "color", config.Color,
+		"Style headline numbers and warnings: auto (only on a terminal), always or never")
+	fs.IntVar(&config.SigFigs, "sig-figs", config.SigFigs,
+		"Round results in the report and CSV to this many significant figures; JSON keeps full precision")
+}
+
+// changed reports whether the named flag was given explicitly.
+func (c *configFlags) changed(name string) bool {
+	return c.flags.Changed(name)
+}
+
+// record remembers the flags given so they can be reapplied over the
+// config file, which takes precedence only over defaults. Call it once
+// after parsing.
+func (c *configFlags) record() {
+	c.given = nil
+	c.flags.Visit(func(f *pflag.Flag) {
+		if !strings.HasSuffix(f.Value.Type(), "Slice") && !strings.HasSuffix(f.Value.Type(), "Array") {
+			c.given = append(c.given, [2]string{f.Name, f.Value.String()})
+		}
+	})
+}
+
+// load resets the config to defaults, applies the --config files left to
+// right, then the SOLARCALC_ environment variables of the flags not
+// given, and then the recorded flags. List flags such as --config are
+// not read from the environment. It returns, for each key the files set,
+// the last file that set it.
+func (c *configFlags) load() (map[string]string, error) {
+	*c.config = DefaultConfig()
+	fileKeys := make(map[string]string)
+	for _, path := range c.paths {
+		keys, err := loadConfigFile(path, c.config)
+		if err != nil {
+			return nil, err
+		}
+		for key := range keys {
+			fileKeys[key] = path
+		}
+	}
+	given := make(map[string]bool, len(c.given))
+	for _, flag := range c.given {
+		given[flag[0]] = true
+	}
+	c.env = make(map[string]string)
+	var errs []error
+	c.flags.VisitAll(func(f *pflag.Flag) {
+		kind := f.Value.Type()
+		if given[f.Name] || strings.HasSuffix(kind, "Slice") || strings.HasSuffix(kind, "Array") {
+			return
+		}
+		name := envVar(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := c.flags.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			return
+		}
+		c.env[f.Name] = name
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, flag := range c.given {
+		if err := c.flags.Set(flag[0], flag[1]); err != nil {
+			return nil, err
+		}
+	}
+	return fileKeys, nil
+}
+
+// sources reports where each config key came from after load.
+func (c *configFlags) sources(fileKeys map[string]string) map[string]string {
+	explicit := func(name string) bool { return c.changed(name) && c.env[name] == "" }
+	return configSources(explicit, c.env, fileKeys)
+}
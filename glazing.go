@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// orientations are the facade directions a window group may face.
+var orientations = []string{"north", "east", "south", "west", "horizontal"}
+
+// facadeLatitudes are the absolute latitudes of the rows of
+// facadeMultipliers.
+var facadeLatitudes = []float64{0, 20, 40, 60}
+
+// facadeMultipliers scale horizontal irradiance to the annual incident
+// irradiance on a vertical facade at each of facadeLatitudes. Toward the
+// equator the low winter sun makes the equator-facing facade gain
+// steadily on the horizontal, while the pole-facing facade sees less
+// direct sun; at the equator the two are alike. Skylights take the
+// horizontal irradiance unscaled.
+var facadeMultipliers = map[string][]float64{
+	"equator": {0.42, 0.55, 0.75, 0.98},
+	"pole":    {0.42, 0.33, 0.30, 0.28},
+	"east":    {0.55, 0.58, 0.60, 0.62},
+	"west":    {0.55, 0.58, 0.60, 0.62},
+}
+
+// defaultFacadeLatitude is assumed when neither --lat nor a bundled
+// location gives one: mid-latitude US.
+const defaultFacadeLatitude = 40.0
+
+// WindowGroup is one set of identical windows, supplied via the config
+// file's window_groups list.
+type WindowGroup struct {
+	Name        string  `json:"name,omitempty"`
+	Area        float64 `json:"area"` // m²
+	SHGC        float64 `json:"shgc"`
+	Orientation string  `json:"orientation"`
+}
+
+// WindowGroupResult is a group's share of the building result.
+type WindowGroupResult struct {
+	WindowGroup
+	SolarReduction     float64 `json:"solar_reduction_kwh_day"`
+	CoolingLoadReduced float64 `json:"cooling_load_reduced_kwh_day"`
+	AnnualCostSaved    float64 `json:"annual_cost_saved_usd"`
+}
+
+// facadeLatitude is the latitude the facade multipliers are taken at:
+// --lat, else the bundled location's, else defaultFacadeLatitude.
+// Southern latitudes are negative.
+func facadeLatitude(config Config) float64 {
+	if config.Latitude != 0 {
+		return config.Latitude
+	}
+	if data, ok := lookupLocation(config.Location); ok {
+		return data.Latitude
+	}
+	return defaultFacadeLatitude
+}
+
+// orientationMultiplier returns the ratio of the annual irradiance on a
+// facade facing orientation to the horizontal irradiance, at latitude.
+// South faces the equator in the northern hemisphere and north does in
+// the southern.
+func orientationMultiplier(orientation string, latitude float64) (float64, bool) {
+	facade := strings.ToLower(strings.TrimSpace(orientation))
+	switch {
+	case facade == "horizontal":
+		return 1, true
+	case facade == "south" && latitude >= 0, facade == "north" && latitude < 0:
+		facade = "equator"
+	case facade == "north" || facade == "south":
+		facade = "pole"
+	}
+	row, ok := facadeMultipliers[facade]
+	if !ok {
+		return 0, false
+	}
+	return interpolate(facadeLatitudes, row, math.Abs(latitude)), true
+}
+
+// interpolate returns y at x, linear between the points (xs[i], ys[i])
+// and clamped to the first and last.
+func interpolate(xs, ys []float64, x float64) float64 {
+	if x <= xs[0] {
+		return ys[0]
+	}
+	for i := 1; i < len(xs); i++ {
+		if x <= xs[i] {
+			t := (x - xs[i-1]) / (xs[i] - xs[i-1])
+			return ys[i-1] + t*(ys[i]-ys[i-1])
+		}
+	}
+	return ys[len(ys)-1]
+}
+
+// groupReductions returns the daily solar reduction for each group, the
+// irradiance on its facade at latitude times its area.
+func groupReductions(groups []WindowGroup, ghi, latitude float64) []float64 {
+	reductions := make([]float64, len(groups))
+	for i, g := range groups {
+		m, _ := orientationMultiplier(g.Orientation, latitude)
+		reductions[i] = ghi * m * g.Area
+	}
+	return reductions
+}
+
+// applyWindowGroups replaces the single reduction and SHGC with the
+// totals for the configured window groups: the total reduction is the sum
+// over groups and the SHGC is the reduction-weighted mean, so the simple
+// model yields the same cooling load as summing each group.
+func applyWindowGroups(config *Config, ghi float64) {
+	reductions := groupReductions(config.WindowGroups, ghi, facadeLatitude(*config))
+
+	var total, weighted float64
+	for i, g := range config.WindowGroups {
+		total += reductions[i]
+		weighted += reductions[i] * g.SHGC
+	}
+
+	config.SolarReduction = total
+	if total > 0 {
+		config.SHGC = weighted / total
+	}
+}
+
+// windowGroupResults splits the building result between the window groups
+// in proportion to the heat each admits.
+func windowGroupResults(config Config, ghi float64, result Result) []WindowGroupResult {
+	reductions := groupReductions(config.WindowGroups, ghi, facadeLatitude(config))
+
+	var totalGain float64
+	for i, g := range config.WindowGroups {
+		totalGain += reductions[i] * g.SHGC
+	}
+
+	groups := make([]WindowGroupResult, len(config.WindowGroups))
+	for i, g := range config.WindowGroups {
+		share := 0.0
+		if totalGain > 0 {
+			share = reductions[i] * g.SHGC / totalGain
+		}
+		groups[i] = WindowGroupResult{
+			WindowGroup:        g,
+			SolarReduction:     reductions[i],
+			CoolingLoadReduced: result.CoolingLoadReduced * share,
+			AnnualCostSaved:    result.AnnualCostSaved * share,
+		}
+	}
+	return groups
+}
+
+// validateWindowGroups reports problems with the configured groups.
+func validateWindowGroups(groups []WindowGroup) []error {
+	var errs []error
+	for i, g := range groups {
+		label := g.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		if g.Area <= 0 {
+			errs = append(errs, fmt.Errorf("Window group %s: area must be positive", label))
+		}
+		if g.SHGC <= 0 || g.SHGC > 1 {
+			errs = append(errs, fmt.Errorf("Window group %s: SHGC must be between 0 and 1", label))
+		}
+		if _, ok := orientationMultiplier(g.Orientation, 0); !ok {
+			errs = append(errs, fmt.Errorf("Window group %s: unknown orientation %q", label, g.Orientation))
+		}
+	}
+	return errs
+}
+
+// checkRetrofitSHGC reports why --shgc-existing and --shgc-proposed cannot
+// derive the reduction for config, or nil when they can.
+func checkRetrofitSHGC(config Config) error {
+	switch {
+	case config.SHGCExisting <= 0 || config.SHGCProposed <= 0:
+		return errors.New("--shgc-existing and --shgc-proposed must be given together")
+	case config.SHGCProposed >= config.SHGCExisting:
+		return errors.New("--shgc-proposed must be lower than --shgc-existing")
+	case config.SolarReduction != 0:
+		return errors.New("--shgc-existing and --shgc-proposed replace --reduction; give one or the other")
+	case len(config.WindowGroups) > 0:
+		return errors.New("--shgc-existing and --shgc-proposed cannot be combined with window_groups")
+	case config.HeatingMode:
+		return errors.New("--shgc-existing and --shgc-proposed cannot be combined with --heating-mode")
+	case config.WindowArea <= 0:
+		return errors.New("--shgc-existing and --shgc-proposed require --window-area")
+	}
+	return nil
+}
+
+// retrofitFraction is the share of the existing solar heat gain the
+// proposed glazing removes.
+func retrofitFraction(config Config) float64 {
+	return (config.SHGCExisting - config.SHGCProposed) / config.SHGCExisting
+}
+
+// applyRetrofitSHGC derives the reduction from an existing and proposed
+// SHGC. The baseline heat gain is the irradiance on the glazed area times
+// the existing SHGC; taking the reduction as the retrofit's share of that
+// irradiance and the SHGC as the existing one makes the simple model's
+// heat gain saved the irradiance times the SHGC difference.
+func applyRetrofitSHGC(config *Config, ghi float64) {
+	config.SolarReduction = ghi * config.WindowArea * retrofitFraction(*config)
+	config.SHGC = config.SHGCExisting
+}
@@ -2,4 +2,10 @@ module github.com/prxsto/solar-calc
 
 go 1.23.2
 
-require github.com/spf13/pflag v1.0.5
+require (
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/spf13/pflag v1.0.5
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenTime stamps every golden output, so the files are byte-stable.
+var goldenTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// checkGolden compares got with testdata/name.golden, rewriting the file
+// instead under -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run go test -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s; run go test -update if the change is intended\ngot:\n%s",
+			name, path, got)
+	}
+}
+
+func TestGoldenOutputs(t *testing.T) {
+	solar := func() (SolarResource, error) { return SolarResource{AnnualGHI: 5.1, Source: "golden"}, nil }
+	tests := []struct {
+		name   string
+		config func(c *Config)
+	}{
+		{"default", func(c *Config) {}},
+		{"heating", func(c *Config) { c.HeatingMode, c.SolarReduction, c.ElectricityCost = true, -100, 0.10 }},
+		{"roof", func(c *Config) { c.RoofArea, c.FloorArea, c.ProjectCost = 100, 500, 2500 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultConfigWith(func(c *Config) {
+				c.SolarReduction, c.ElectricityCost, c.Color = 100, 0.15, "never"
+				tt.config(c)
+			})
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			result, err := computeResult(config, solar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output := resultOutputAt(result, config, goldenTime)
+			format, err := outputFormatOf(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var report, jsonOut, csvOut bytes.Buffer
+			printResult(&report, result, config, false)
+			if err := renderJSON(&jsonOut, output, false); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeCSV(&csvOut, []ResultOutput{output}, format); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.name+".txt", report.Bytes())
+			checkGolden(t, tt.name+".json", jsonOut.Bytes())
+			checkGolden(t, tt.name+".csv", csvOut.Bytes())
+		})
+	}
+}
+
+func TestGoldenBatch(t *testing.T) {
+	scenarios := "name,solar_reduction,ac_cop,operating_hours\n" +
+		"clinic,100,3,24\n" +
+		"efficient,100,4.5,24\n" +
+		"office,150.5,3.5,10\n"
+	base := defaultConfigWith(func(c *Config) {
+		c.ElectricityCost, c.OutputTimestamp = 0.15, goldenTime.Format(time.RFC3339)
+	})
+	format, err := outputFormatOf(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	stream, err := newResultStream(dir, "batch", format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runBatch(strings.NewReader(scenarios), base, map[string]string{}, batchOptions{}, stream.add); err != nil {
+		stream.discard()
+		t.Fatal(err)
+	}
+	jsonPath, csvPath, err := stream.commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, path := range map[string]string{"batch.json": jsonPath, "batch.csv": csvPath} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, name, data)
+	}
+}
+
+func TestGoldenManifest(t *testing.T) {
+	config := defaultConfigWith(func(c *Config) { c.SolarReduction, c.ElectricityCost = 100, 0.15 })
+	sources := make(map[string]string, len(configFields))
+	for _, field := range configFields {
+		sources[field.Key] = "default"
+	}
+	sources["solar_reduction"], sources["electricity_cost"] = "flag", "flag"
+	result, err := computeResult(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := manifestData("manifest.md", assumptionsManifest(config, sources, result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "manifest.md", data)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// headlineMetric is a result --headline-only can print on its own.
+type headlineMetric struct {
+	Value    func(result Result, config Config) float64
+	Decimals int
+}
+
+// headlineMetrics are the --headline-metric choices.
+var headlineMetrics = map[string]headlineMetric{
+	// $/year after any roof savings and heating penalty
+	"annual-savings": {func(r Result, _ Config) float64 { return r.NetAnnualSavings }, 2},
+	// $/year, the net savings plus any --carbon-price value
+	"total-benefit": {func(r Result, _ Config) float64 { return r.NetAnnualSavings + r.CarbonValue }, 2},
+	// kWh/year
+	"electricity-saved": {func(r Result, _ Config) float64 { return r.ElectricitySaved * 365 * r.OperatingFactor }, 0},
+	// kW electric
+	"peak-reduction": {func(r Result, _ Config) float64 { return r.PeakElectricityReduced }, 2},
+	// in the --co2-unit per year
+	"co2-avoided": {func(r Result, c Config) float64 {
+		unit, _ := lookupCO2Unit(c.CO2Unit)
+		return r.AnnualCO2Avoided * unit.PerKg
+	}, 1},
+	// years; needs --project-cost
+	"payback": {func(r Result, _ Config) float64 { return r.PaybackYears }, 1},
+}
+
+// headlineNames lists the headline metrics, sorted.
+func headlineNames() []string {
+	names := make([]string, 0, len(headlineMetrics))
+	for name := range headlineMetrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// checkHeadlineMetric reports an unknown metric, or one config cannot
+// produce.
+func checkHeadlineMetric(name string, config Config) error {
+	if _, ok := headlineMetrics[name]; !ok {
+		return fmt.Errorf("unknown headline metric %q, expected one of %s", name, strings.Join(headlineNames(), ", "))
+	}
+	if name == "payback" && config.ProjectCost <= 0 {
+		return errors.New("headline metric payback requires --project-cost")
+	}
+	return nil
+}
+
+// headlineValue formats the named metric of result as a bare number,
+// rounded to --sig-figs when set. A project that never pays back is
+// "never".
+func headlineValue(name string, result Result, config Config) string {
+	metric := headlineMetrics[name]
+	value := metric.Value(result, config)
+	if math.IsInf(value, 1) {
+		return "never"
+	}
+	return formatFigures(value, metric.Decimals, config.SigFigs)
+}
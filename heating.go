@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// calculateHeatingSavings prices the extra solar gain admitted in a
+// heating-dominated climate, where SolarReduction is negative. The useful
+// gain follows the cooling chain except for the medical equipment factor,
+// which only applies to cooling loads, and offsets heating delivered at
+// HeatingCOP and priced at HeatingCost (the electricity cost when unset).
+// The peak cooling quantities do not apply and are left at zero.
+func calculateHeatingSavings(config Config) Result {
+	lagFactor, _, lagHours := timeLag(config)
+	heatingLoadReduced := math.Abs(config.SolarReduction) *
+		config.SHGC *
+		config.TransmissionFactor *
+		lagFactor
+
+	energySaved := heatingLoadReduced / config.HeatingCOP
+	operatingFactor := scheduleFactor(config)
+
+	cost := config.HeatingCost
+	costSource := "heating cost input"
+	if cost <= 0 {
+		cost = config.ElectricityCost
+		costSource = "input"
+		if config.CostSource != "" {
+			costSource = config.CostSource
+		}
+	}
+
+	return Result{
+		Mode:                 "heating",
+		TotalSolarReduction:  config.SolarReduction,
+		CoolingLoadReduced:   heatingLoadReduced,
+		ElectricitySaved:     energySaved,
+		GridElectricitySaved: energySaved,
+		OperatingFactor:      operatingFactor,
+		TimeLagHours:         lagHours,
+		OperatingDaysPerYear: config.OperatingDaysPerYear,
+		AnnualCostSaved:      energySaved * cost * 365 * operatingFactor,
+		Assumptions: Assumptions{
+			Location:              config.Location,
+			BuildingType:          "Medical Clinic",
+			AC_COP:                config.HeatingCOP,
+			SHGC:                  config.SHGC,
+			WWR:                   config.WWR,
+			TransmissionFactor:    config.TransmissionFactor,
+			TimeLagFactor:         lagFactor,
+			MedicalEquipFactor:    1,
+			ElectricityCost:       cost,
+			ElectricityCostSource: costSource,
+			OperatingHours:        config.OperatingHours,
+			OperatingDays:         config.OperatingDays,
+			Units: Units{
+				SolarRadiation:  "kWh/day",
+				CoolingLoad:     "kWh thermal/day",
+				Electricity:     "kWh heating energy/day",
+				PeakCooling:     "kW thermal",
+				PeakElectricity: "kW electric",
+				Cost:            "$/kWh",
+				Savings:         "$/year",
+			},
+		},
+	}
+}
+
+// heatingPenalty is the annual cost of the winter solar gain a cooling
+// retrofit blocks: for HeatingSeasonFraction of the year the gain would
+// have offset heating at HeatingCOP, priced like heating-mode savings.
+// It is 0 when no heating season is set. Those days save no cooling, so
+// coolingSeason takes them out of the cooling savings.
+func heatingPenalty(config Config, operatingFactor float64) float64 {
+	if config.HeatingSeasonFraction <= 0 || config.HeatingMode {
+		return 0
+	}
+	lagFactor, _, _ := timeLag(config)
+	lostGain := config.SolarReduction * config.SHGC * config.TransmissionFactor * lagFactor
+	cost := config.HeatingCost
+	if cost <= 0 {
+		cost = config.ElectricityCost
+	}
+	return lostGain / config.HeatingCOP * cost * 365 * operatingFactor * config.HeatingSeasonFraction
+}
+
+// coolingSeason is the share of the year the cooling savings accrue
+// over: all of it, or with a heating season set, the rest of the year.
+func coolingSeason(config Config) float64 {
+	if config.HeatingSeasonFraction <= 0 || config.HeatingMode {
+		return 1
+	}
+	return 1 - config.HeatingSeasonFraction
+}
+
+// checkPositiveNet is --require-positive-net: an error when the heating
+// penalty outweighs the cooling savings, so the retrofit would raise the
+// building's total energy cost.
+func checkPositiveNet(result Result) error {
+	if result.NetAnnualSavings >= 0 {
+		return nil
+	}
+	return fmt.Errorf("net annual savings are negative (%.2f $/year): the heating penalty of %.2f $/year outweighs the cooling savings; this retrofit is counterproductive here",
+		result.NetAnnualSavings, result.HeatingPenalty)
+}
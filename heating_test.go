@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHeatingSeasonIsNotAlsoCountedAsCooling(t *testing.T) {
+	tests := []struct {
+		name     string
+		fraction float64
+		heating  bool
+		season   float64
+	}{
+		{"no heating season", 0, false, 1},
+		{"30% heating", 0.3, false, 0.7},
+		{"all heating", 1, false, 0},
+		{"heating mode ignores the season", 0.3, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.SolarReduction, config.ElectricityCost = 100, 0.15
+			if tt.heating {
+				config.HeatingMode, config.SolarReduction = true, -100
+			}
+			yearRound := calculateCoolingSavings(config)
+			config.HeatingSeasonFraction = tt.fraction
+			result, err := computeResult(config, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := coolingSeason(config); got != tt.season {
+				t.Errorf("coolingSeason = %g, want %g", got, tt.season)
+			}
+			if want := yearRound.AnnualCostSaved * tt.season; math.Abs(result.AnnualCostSaved-want) > 1e-9 {
+				t.Errorf("AnnualCostSaved = %g, want %g", result.AnnualCostSaved, want)
+			}
+			if want := heatingPenalty(config, result.OperatingFactor); result.HeatingPenalty != want {
+				t.Errorf("HeatingPenalty = %g, want %g", result.HeatingPenalty, want)
+			}
+			if want := result.AnnualCostSaved - result.HeatingPenalty; math.Abs(result.NetAnnualSavings-want) > 1e-9 {
+				t.Errorf("NetAnnualSavings = %g, want %g", result.NetAnnualSavings, want)
+			}
+		})
+	}
+}
+
+func TestHeatingSeasonAlsoShortensTheRoofSavings(t *testing.T) {
+	solar := func() (SolarResource, error) { return SolarResource{AnnualGHI: 5.1, Source: "test"}, nil }
+	config := defaultConfigWith(func(c *Config) {
+		c.SolarReduction, c.ElectricityCost, c.RoofArea = 100, 0.15, 200
+	})
+	yearRound, err := computeResult(config, solar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.HeatingSeasonFraction = 0.3
+	result, err := computeResult(config, solar)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := yearRound.Roof.AnnualCostSaved * 0.7; math.Abs(result.Roof.AnnualCostSaved-want) > 1e-9 {
+		t.Errorf("roof AnnualCostSaved = %g, want %g", result.Roof.AnnualCostSaved, want)
+	}
+	if want := yearRound.AnnualCO2Avoided * 0.7; math.Abs(result.AnnualCO2Avoided-want) > 1e-9 {
+		t.Errorf("AnnualCO2Avoided = %g, want %g with the roof's share", result.AnnualCO2Avoided, want)
+	}
+	if result.Roof.ElectricitySaved != yearRound.Roof.ElectricitySaved || result.CO2Avoided != yearRound.CO2Avoided {
+		t.Error("the daily roof figures changed with the heating season")
+	}
+	want := result.AnnualCostSaved + result.Roof.AnnualCostSaved - result.HeatingPenalty
+	if math.Abs(result.NetAnnualSavings-want) > 1e-9 {
+		t.Errorf("NetAnnualSavings = %g, want %g", result.NetAnnualSavings, want)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigPath is where init writes when no path is given.
+const defaultConfigPath = "solar-calc.yaml"
+
+// runInit is the init command: write a config file holding every key at
+// its default value, each commented with its flag's help text.
+func runInit(args []string) int {
+	var force, yes bool
+
+	fs := pflag.NewFlagSet("init", pflag.ExitOnError)
+	fs.BoolVar(&force, "force", false,
+		"Overwrite the file if it already exists, after confirming")
+	fs.BoolVarP(&yes, "yes", "y", false,
+		"Confirm --force without prompting, as scripts must")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  calculator init [--force [--yes]] [path]\n\n")
+		fmt.Fprintf(os.Stderr, "Writes a commented YAML config file with every key at its default value\n")
+		fmt.Fprintf(os.Stderr, "(default path: %s). Use it with --config.\n\n", defaultConfigPath)
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	path := defaultConfigPath
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		path = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	data, err := defaultConfigYAML()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		if _, err := os.Stat(path); err == nil &&
+			!confirmDestructive(os.Stdin, os.Stderr, fmt.Sprintf("Overwrite %s?", path), yes) {
+			fmt.Printf("Error: not overwriting %s; confirm, or pass --yes when not on a terminal\n", path)
+			return 1
+		}
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, mode, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		fmt.Printf("Error: %s already exists; use --force to overwrite it\n", path)
+		return 1
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Printf("Error: failed to write %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("Default configuration written to %s\n", path)
+	return 0
+}
+
+// fileOnlyKeys documents the config keys that have no flag, with an
+// example value since they are empty by default.
+var fileOnlyKeys = map[string]struct {
+	help    string
+	example any
+}{
+	"window_groups": {
+		"Window groups replacing --reduction and --shgc: area in m², SHGC and orientation",
+		[]WindowGroup{{Name: "south", Area: 20, SHGC: 0.25, Orientation: "south"}},
+	},
+	"confidence": {
+		"Confidence of each input (measured, estimated or default), shown in the results",
+		map[string]string{"shgc": confidenceMeasured, "ac_cop": confidenceEstimated},
+	},
+}
+
+// defaultConfigYAML renders DefaultConfig as YAML, one key per Config
+// field in declaration order. Each key is commented with the help text of
+// its flag, so the file tracks the flags without being maintained by hand.
+func defaultConfigYAML() ([]byte, error) {
+	config := DefaultConfig()
+	flags := pflag.NewFlagSet("init", pflag.ContinueOnError)
+	bindConfigFlags(flags, &config)
+	bindOutputFlags(flags, &config)
+	flagOf := make(map[string]string, len(configFields))
+	for _, field := range configFields {
+		flagOf[field.Key] = field.Flag
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Solar cooling calculator configuration (v%s).\n", version)
+	fmt.Fprintf(&buf, "# Every key is optional; flags override the values here.\n")
+
+	v := reflect.ValueOf(config)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		if fileOnly, ok := fileOnlyKeys[key]; ok {
+			fmt.Fprintf(&buf, "\n# %s (config file only)\n", fileOnly.help)
+			example, err := yaml.Marshal(map[string]any{key: fileOnly.example})
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range strings.Split(strings.TrimSpace(string(example)), "\n") {
+				fmt.Fprintf(&buf, "# %s\n", line)
+			}
+			continue
+		}
+		if flag := flags.Lookup(flagOf[key]); flag != nil {
+			fmt.Fprintf(&buf, "\n# %s (--%s)\n", flag.Usage, flag.Name)
+		} else {
+			fmt.Fprintf(&buf, "\n")
+		}
+		value, err := yaml.Marshal(map[string]any{key: v.Field(i).Interface()})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	return buf.Bytes(), nil
+}
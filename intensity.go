@@ -0,0 +1,41 @@
+package main
+
+// SavingsIntensity normalizes the savings by building size so projects of
+// different sizes can be benchmarked. Per m² of glazing it covers the
+// windows alone; per m² of floor, like an energy use intensity, it covers
+// the whole project: the net savings and any cool-roof electricity.
+type SavingsIntensity struct {
+	GlazedArea          float64 `json:"glazed_area_m2,omitempty"`
+	CostPerGlazedArea   float64 `json:"savings_usd_per_m2_glazing_year,omitempty"`
+	EnergyPerGlazedArea float64 `json:"electricity_saved_kwh_per_m2_glazing_year,omitempty"`
+	FloorArea           float64 `json:"floor_area_m2,omitempty"`
+	CostPerFloorArea    float64 `json:"savings_usd_per_m2_floor_year,omitempty"`
+	EnergyPerFloorArea  float64 `json:"electricity_saved_kwh_per_m2_floor_year,omitempty"`
+}
+
+// calculateSavingsIntensity divides result by the glazed and floor areas
+// given, returning nil when neither is known.
+func calculateSavingsIntensity(result Result, config Config) *SavingsIntensity {
+	glazed := config.glazedArea()
+	if glazed <= 0 && config.FloorArea <= 0 {
+		return nil
+	}
+
+	windowKWh := result.ElectricitySaved * 365 * result.OperatingFactor
+	intensity := &SavingsIntensity{}
+	if glazed > 0 {
+		intensity.GlazedArea = glazed
+		intensity.CostPerGlazedArea = result.AnnualCostSaved / glazed
+		intensity.EnergyPerGlazedArea = windowKWh / glazed
+	}
+	if config.FloorArea > 0 {
+		projectKWh := windowKWh
+		if result.Roof != nil {
+			projectKWh += result.Roof.ElectricitySaved * 365 * result.OperatingFactor
+		}
+		intensity.FloorArea = config.FloorArea
+		intensity.CostPerFloorArea = result.NetAnnualSavings / config.FloorArea
+		intensity.EnergyPerFloorArea = projectKWh / config.FloorArea
+	}
+	return intensity
+}
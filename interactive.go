@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stdinIsTerminal reports whether stdin is attached to an interactive
+// terminal rather than a pipe or file.
+func stdinIsTerminal() bool {
+	return isTerminal(os.Stdin)
+}
+
+// promptFloat asks for a float on out, reading answers from in until
+// valid returns nil. An empty answer selects def when def is non-zero.
+func promptFloat(in *bufio.Reader, out io.Writer, label string, def float64, valid func(float64) error) (float64, error) {
+	for {
+		if def != 0 {
+			fmt.Fprintf(out, "%s [%g]: ", label, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", label)
+		}
+
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return 0, fmt.Errorf("failed to read input: %v", err)
+		}
+		line = strings.TrimSpace(line)
+
+		if line == "" && def != 0 {
+			return def, nil
+		}
+
+		value, perr := strconv.ParseFloat(line, 64)
+		if perr != nil {
+			fmt.Fprintf(out, "  %q is not a number, try again\n", line)
+			continue
+		}
+		if verr := valid(value); verr != nil {
+			fmt.Fprintf(out, "  %v, try again\n", verr)
+			continue
+		}
+		return value, nil
+	}
+}
+
+func positive(name string) func(float64) error {
+	return func(v float64) error {
+		if v <= 0 {
+			return fmt.Errorf("%s must be a positive number", name)
+		}
+		return nil
+	}
+}
+
+func negative(name string) func(float64) error {
+	return func(v float64) error {
+		if v >= 0 {
+			return fmt.Errorf("%s must be negative in heating mode (added solar gain)", name)
+		}
+		return nil
+	}
+}
+
+func between(name string, lo, hi float64) func(float64) error {
+	return func(v float64) error {
+		if v < lo || v > hi {
+			return fmt.Errorf("%s must be between %g and %g", name, lo, hi)
+		}
+		return nil
+	}
+}
+
+func fraction(name string) func(float64) error {
+	return func(v float64) error {
+		if v <= 0 || v > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+		return nil
+	}
+}
+
+// promptMissing fills in the required inputs that were not given on the
+// command line, then offers the main assumptions with their defaults so
+// they can be accepted by pressing enter. Flags named in set are skipped.
+// Each answer is checked as Validate will check it.
+func promptMissing(config *Config, set func(name string) bool, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+
+	reductionLabel, reduction := "Total solar radiation reduction (kWh/day)", positive("Solar reduction")
+	if config.HeatingMode {
+		reductionLabel, reduction = "Added solar gain, as a negative reduction (kWh/day)", negative("Solar reduction")
+	}
+	cop := positive("COP")
+	if config.COPMin > 0 && config.COPMax > config.COPMin {
+		cop = between("COP", config.COPMin, config.COPMax)
+	}
+
+	prompts := []struct {
+		flag  string
+		label string
+		value *float64
+		valid func(float64) error
+	}{
+		{"reduction", reductionLabel, &config.SolarReduction, reduction},
+		{"cost", "Electricity cost ($/kWh)", &config.ElectricityCost, positive("Electricity cost")},
+		{"cop", "AC Coefficient of Performance", &config.AC_COP, cop},
+		{"shgc", "Solar Heat Gain Coefficient", &config.SHGC, fraction("SHGC")},
+		{"wwr", "Window to Wall Ratio", &config.WWR, fraction("WWR")},
+	}
+
+	for _, p := range prompts {
+		if set(p.flag) {
+			continue
+		}
+		v, err := promptFloat(reader, out, p.label, *p.value, p.valid)
+		if err != nil {
+			return err
+		}
+		*p.value = v
+	}
+
+	return nil
+}
+
+// confirmDestructive asks on out whether to go ahead with an operation
+// that destroys data, described by question. yes (--yes) skips the
+// prompt. Without a terminal to ask on it declines, so scripts get the
+// safe behaviour unless they pass --yes.
+func confirmDestructive(in io.Reader, out io.Writer, question string, yes bool) bool {
+	if yes {
+		return true
+	}
+	if !stdinIsTerminal() {
+		return false
+	}
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
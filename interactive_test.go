@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptMissingChecksAnswersLikeValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		heating   bool
+		answers   string // reduction, cost, cop, then shgc and wwr at their defaults
+		reduction float64
+		cop       float64
+		retries   []string
+	}{
+		{"cooling", false, "100\n0.15\n3.5\n\n\n", 100, 3.5, nil},
+		{"cooling refuses a gain", false, "-100\n100\n0.15\n\n\n\n", 100, 3, []string{"must be a positive number"}},
+		{"heating takes a gain", true, "-100\n0.15\n\n\n\n", -100, 3, nil},
+		{"heating refuses a reduction", true, "100\n-100\n0.15\n\n\n\n", -100, 3, []string{"must be negative in heating mode"}},
+		{"implausible COP", false, "100\n0.15\n35\n0.5\n3.5\n\n\n", 100, 3.5,
+			[]string{"COP must be between 1 and 15", "COP must be between 1 and 15"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.SolarReduction, config.ElectricityCost, config.AC_COP = 0, 0, 3
+			config.HeatingMode = tt.heating
+			var out strings.Builder
+			err := promptMissing(&config, func(string) bool { return false }, strings.NewReader(tt.answers), &out)
+			if err != nil {
+				t.Fatalf("promptMissing: %v\n%s", err, out.String())
+			}
+			if config.SolarReduction != tt.reduction || config.ElectricityCost != 0.15 || config.AC_COP != tt.cop {
+				t.Errorf("got reduction %g, cost %g, COP %g; want %g, 0.15, %g",
+					config.SolarReduction, config.ElectricityCost, config.AC_COP, tt.reduction, tt.cop)
+			}
+			if got := strings.Count(out.String(), "try again"); got != len(tt.retries) {
+				t.Errorf("%d retries, want %d:\n%s", got, len(tt.retries), out.String())
+			}
+			for _, retry := range tt.retries {
+				if !strings.Contains(out.String(), retry) {
+					t.Errorf("output lacks %q:\n%s", retry, out.String())
+				}
+			}
+			if err := config.Validate(); err != nil {
+				t.Errorf("Validate after prompting: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import "math"
+
+// Quantity is a value with its units, as dumped by --dump-intermediates.
+type Quantity struct {
+	Value float64 `json:"value"`
+	Units string  `json:"units"`
+}
+
+// loadStep is one named product of the load calculation.
+type loadStep struct {
+	Name string
+	Quantity
+}
+
+// loadSteps are the factors of the default model's daily load, one
+// product per factor calculateCoolingSavings or calculateHeatingSavings
+// applies, in order. The last step is the load itself.
+func loadSteps(result Result, config Config) []loadStep {
+	thermal := "kWh thermal/day"
+	reduction := config.SolarReduction
+	if config.HeatingMode {
+		// Heating mode prices the admitted gain, given as a negative
+		// reduction.
+		reduction = math.Abs(reduction)
+	}
+	heatGain := reduction * config.SHGC
+	transmitted := heatGain * config.TransmissionFactor
+	lagged := transmitted * result.Assumptions.TimeLagFactor
+	steps := []loadStep{
+		{"solar_reduction", Quantity{config.SolarReduction, "kWh/day"}},
+		{"solar_heat_gain", Quantity{heatGain, thermal}},
+		{"transmitted_heat_gain", Quantity{transmitted, thermal}},
+		{"time_lagged_heat_gain", Quantity{lagged, thermal}},
+	}
+	if config.HeatingMode {
+		return append(steps, loadStep{"heating_load_reduced", Quantity{lagged, thermal}})
+	}
+	solar := lagged * result.Assumptions.MedicalEquipFactor
+	conduction := conductionLoad(config)
+	return append(steps,
+		loadStep{"solar_load_reduced", Quantity{solar, thermal}},
+		loadStep{"conduction_load_reduced", Quantity{conduction, thermal}},
+		loadStep{"cooling_load_reduced", Quantity{solar + conduction, thermal}},
+	)
+}
+
+// intermediates lists every step of the window calculation so the math
+// can be checked independently of the final fields. The load steps are
+// recomputed with the default model's formula, so under another --model
+// the last of them may differ from CoolingLoadReduced.
+func intermediates(result Result, config Config) map[string]Quantity {
+	annualFactor := 365 * result.OperatingFactor
+	units := result.Assumptions.Units
+	annualUnits := "kWh electric/year"
+	cop := systemCOP(config)
+	if config.HeatingMode {
+		annualUnits = "kWh heating energy/year"
+		cop = config.HeatingCOP
+	}
+
+	quantities := map[string]Quantity{
+		"medical_equip_factor":          {result.Assumptions.MedicalEquipFactor, "factor"},
+		"time_lag_factor":               {result.Assumptions.TimeLagFactor, "factor"},
+		"system_cop":                    {cop, "ratio"},
+		"electricity_saved":             {result.ElectricitySaved, units.Electricity},
+		"grid_electricity_saved":        {result.GridElectricitySaved, units.Electricity},
+		"operating_factor":              {result.OperatingFactor, "fraction"},
+		"annual_electricity_saved":      {result.ElectricitySaved * annualFactor, annualUnits},
+		"annual_grid_electricity_saved": {result.GridElectricitySaved * annualFactor, annualUnits},
+		"peak_hours":                    {peakHours(config), "h/day"},
+		"peak_cooling_reduced":          {result.PeakCoolingReduced, "kW thermal"},
+		"peak_electricity_reduced":      {result.PeakElectricityReduced, "kW electric"},
+		"peak_tons_reduced":             {result.PeakTonsReduced, "tons"},
+		"annual_cost_saved":             {result.AnnualCostSaved, "$/year"},
+	}
+	for _, s := range loadSteps(result, config) {
+		quantities[s.Name] = s.Quantity
+	}
+	return quantities
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoadStepsMultiplyOutToTheLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(c *Config)
+	}{
+		{"default", func(c *Config) {}},
+		{"medical equipment", func(c *Config) { c.MedicalEquipFactor = 1.4 }},
+		{"equipment schedule", func(c *Config) { c.EquipSchedule = "08:00-12:00=1.6" }},
+		{"conduction", func(c *Config) { c.UFactor, c.DeltaT, c.WindowArea = 1.1, 6, 20 }},
+		{"thermal mass", func(c *Config) { c.ThermalMass = "medium" }},
+		{"heating", func(c *Config) { c.HeatingMode, c.SolarReduction = true, -100 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.SolarReduction, config.ElectricityCost = 100, 0.15
+			tt.config(&config)
+			result := calculateCoolingSavings(config)
+
+			steps := loadSteps(result, config)
+			last := steps[len(steps)-1]
+			if math.Abs(last.Value-result.CoolingLoadReduced) > 1e-9 {
+				t.Errorf("last step %s = %g, CoolingLoadReduced = %g",
+					last.Name, last.Value, result.CoolingLoadReduced)
+			}
+			if got := intermediates(result, config)[last.Name]; got != last.Quantity {
+				t.Errorf("intermediates[%s] = %v, want %v", last.Name, got, last.Quantity)
+			}
+		})
+	}
+}
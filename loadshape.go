@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// halfHours is the number of points in a daily load shape.
+const halfHours = 48
+
+// loadShapeTolerance is how far a load shape may sum from 1.
+const loadShapeTolerance = 0.001
+
+// readLoadShape reads a normalized daily cooling load shape: 48
+// half-hourly fractions of the daily load, from midnight, one per line.
+// A line may hold a time label before the fraction, and a header line
+// is skipped.
+func readLoadShape(r io.Reader) ([]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var shape []float64
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		text := strings.TrimSpace(record[len(record)-1])
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid fraction %q", line, text)
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("line %d: fraction cannot be negative", line)
+		}
+		shape = append(shape, value)
+	}
+	if len(shape) != halfHours {
+		return nil, fmt.Errorf("expected %d half-hourly values, got %d", halfHours, len(shape))
+	}
+	var sum float64
+	for _, v := range shape {
+		sum += v
+	}
+	if math.Abs(sum-1) > loadShapeTolerance {
+		return nil, fmt.Errorf("fractions sum to %.4f, expected 1", sum)
+	}
+	return shape, nil
+}
+
+// loadShapeFile reads the load shape at path.
+func loadShapeFile(path string) ([]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	shape, err := readLoadShape(file)
+	if err != nil {
+		return nil, fmt.Errorf("load shape %s: %v", path, err)
+	}
+	return shape, nil
+}
+
+// parseClock parses HH:MM on the half hour into a half-hour index, with
+// 24:00 as the end of the day.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || (minute != 0 && minute != 30) ||
+		hour < 0 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q, expected HH:00 or HH:30", s)
+	}
+	return hour*2 + minute/30, nil
+}
+
+// touRates expands the TOU periods, "16:00-21:00=0.35,12:00-16:00=0.20",
+// into a rate for every half hour. Hours outside any period are charged
+// base, the flat electricity cost.
+func touRates(periods string, base float64) ([halfHours]float64, error) {
+	rates, err := halfHourly(periods, base)
+	if err != nil {
+		return rates, fmt.Errorf("TOU periods: %v", err)
+	}
+	return rates, nil
+}
+
+// halfHourly expands periods of the form HH:MM-HH:MM=value, separated by
+// commas, into a value for every half hour, with base outside them.
+// Later periods override earlier ones, and a period past midnight, such
+// as 22:00-06:00, wraps around. Values cannot be negative.
+func halfHourly(periods string, base float64) ([halfHours]float64, error) {
+	var values [halfHours]float64
+	for i := range values {
+		values[i] = base
+	}
+	if strings.TrimSpace(periods) == "" {
+		return values, nil
+	}
+	for _, period := range strings.Split(periods, ",") {
+		span, valueText, ok := strings.Cut(period, "=")
+		from, to, ok2 := strings.Cut(span, "-")
+		if !ok || !ok2 {
+			return values, fmt.Errorf("invalid period %q, expected HH:MM-HH:MM=value", period)
+		}
+		start, err := parseClock(from)
+		if err != nil {
+			return values, err
+		}
+		end, err := parseClock(to)
+		if err != nil {
+			return values, err
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(valueText), 64)
+		if err != nil || value < 0 {
+			return values, fmt.Errorf("invalid value %q in period %q", valueText, period)
+		}
+		if start == end {
+			return values, fmt.Errorf("period %q is empty", period)
+		}
+		n := (end - start + halfHours) % halfHours
+		if n == 0 {
+			n = halfHours // 00:00-24:00
+		}
+		for i := 0; i < n; i++ {
+			values[(start+i)%halfHours] = value
+		}
+	}
+	return values, nil
+}
+
+// shapedRate is the rate the load shape weights the TOU schedule to: the
+// average $/kWh of saved electricity distributed over the day.
+func shapedRate(shape []float64, rates [halfHours]float64) float64 {
+	var rate float64
+	for i, fraction := range shape {
+		rate += fraction * rates[i]
+	}
+	return rate
+}
+
+// shapedPeak is the peak power in kW of a daily energy distributed by
+// the load shape: the largest half-hour's energy over half an hour.
+func shapedPeak(dailyEnergy float64, shape []float64) float64 {
+	var largest float64
+	for _, fraction := range shape {
+		largest = math.Max(largest, fraction)
+	}
+	return dailyEnergy * largest * 2
+}
+
+// applyLoadShape reads config's load shape file.
+func applyLoadShape(config *Config) error {
+	if config.HeatingMode {
+		return errors.New("--load-shape applies to cooling and cannot be combined with --heating-mode")
+	}
+	shape, err := loadShapeFile(config.LoadShape)
+	if err != nil {
+		return err
+	}
+	config.LoadShapeValues = shape
+	return nil
+}
+
+// shapedRateOf reports rate as the shaped rate when config has a load
+// shape, and 0 otherwise.
+func shapedRateOf(config Config, rate float64) float64 {
+	if len(config.LoadShapeValues) != halfHours {
+		return 0
+	}
+	return rate
+}
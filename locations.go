@@ -0,0 +1,54 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// LocationData holds the bundled reference values for a known location.
+type LocationData struct {
+	State       string
+	Latitude    float64
+	Longitude   float64
+	GHI         float64 // kWh/m²/day, annual average global horizontal irradiance
+	ClimateZone string  // IECC climate zone, e.g. 3B
+	Subregion   string  // eGRID subregion
+	GridCO2     float64 // kg CO2e/kWh, average grid emission rate
+	MarginalCO2 float64 // kg CO2e/kWh, non-baseload emission rate
+}
+
+// locations is the bundled fallback table used when no live data source
+// is available. Irradiance values are NSRDB annual averages, rounded;
+// grid emission rates are eGRID2022 subregion output rates and marginal
+// rates the eGRID2022 non-baseload output rates, rounded.
+var locations = map[string]LocationData{
+	"sacramento":    {State: "CA", ClimateZone: "3B", Latitude: 38.58, Longitude: -121.49, GHI: 5.10, Subregion: "CAMX", GridCO2: 0.23, MarginalCO2: 0.41},
+	"los angeles":   {State: "CA", ClimateZone: "3B", Latitude: 34.05, Longitude: -118.24, GHI: 5.40, Subregion: "CAMX", GridCO2: 0.23, MarginalCO2: 0.41},
+	"san francisco": {State: "CA", ClimateZone: "3C", Latitude: 37.77, Longitude: -122.42, GHI: 4.80, Subregion: "CAMX", GridCO2: 0.23, MarginalCO2: 0.41},
+	"phoenix":       {State: "AZ", ClimateZone: "2B", Latitude: 33.45, Longitude: -112.07, GHI: 5.90, Subregion: "AZNM", GridCO2: 0.36, MarginalCO2: 0.52},
+	"las vegas":     {State: "NV", ClimateZone: "3B", Latitude: 36.17, Longitude: -115.14, GHI: 5.80, Subregion: "NWPP", GridCO2: 0.28, MarginalCO2: 0.55},
+	"denver":        {State: "CO", ClimateZone: "5B", Latitude: 39.74, Longitude: -104.99, GHI: 4.90, Subregion: "RMPA", GridCO2: 0.55, MarginalCO2: 0.74},
+	"houston":       {State: "TX", ClimateZone: "2A", Latitude: 29.76, Longitude: -95.37, GHI: 4.60, Subregion: "ERCT", GridCO2: 0.37, MarginalCO2: 0.49},
+	"miami":         {State: "FL", ClimateZone: "1A", Latitude: 25.76, Longitude: -80.19, GHI: 5.00, Subregion: "FRCC", GridCO2: 0.38, MarginalCO2: 0.44},
+	"atlanta":       {State: "GA", ClimateZone: "3A", Latitude: 33.75, Longitude: -84.39, GHI: 4.60, Subregion: "SRSO", GridCO2: 0.39, MarginalCO2: 0.55},
+	"chicago":       {State: "IL", ClimateZone: "5A", Latitude: 41.88, Longitude: -87.63, GHI: 3.90, Subregion: "RFCW", GridCO2: 0.47, MarginalCO2: 0.72},
+	"new york":      {State: "NY", ClimateZone: "4A", Latitude: 40.71, Longitude: -74.01, GHI: 3.90, Subregion: "NYCW", GridCO2: 0.40, MarginalCO2: 0.46},
+	"seattle":       {State: "WA", ClimateZone: "4C", Latitude: 47.61, Longitude: -122.33, GHI: 3.40, Subregion: "NWPP", GridCO2: 0.28, MarginalCO2: 0.55},
+}
+
+// lookupLocation finds the bundled data for a location name, ignoring
+// case and surrounding whitespace.
+func lookupLocation(name string) (LocationData, bool) {
+	data, ok := locations[strings.ToLower(strings.TrimSpace(name))]
+	return data, ok
+}
+
+// locationNames lists the bundled locations in alphabetical order.
+func locationNames() []string {
+	names := make([]string, 0, len(locations))
+	for name := range locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,332 +1,559 @@
 package main
 
 import (
-	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
-
-	"github.com/spf13/pflag"
 )
 
+// Units labels every reported quantity. Energy is per day and power is
+// the instantaneous peak; thermal and electric quantities are kept apart
+// since they differ by the COP.
 type Units struct {
-	SolarRadiation string // kWh/day
-	CoolingLoad    string // kWh/day
-	Electricity    string // kWh/day
-	Cost           string // $/kWh
-	Savings        string // $/day
+	SolarRadiation  string // kWh/day
+	CoolingLoad     string // kWh thermal/day
+	Electricity     string // kWh electric/day
+	PeakCooling     string // kW thermal
+	PeakElectricity string // kW electric
+	Cost            string // $/kWh
+	Savings         string // $/year
 }
 
 type Assumptions struct {
-	Units              Units
-	Location           string
-	BuildingType       string
-	AC_COP             float64
-	SHGC               float64
-	WWR                float64
-	TransmissionFactor float64
-	TimeLagFactor      float64
-	MedicalEquipFactor float64
-	ElectricityCost    float64
+	Units                 Units
+	Location              string
+	BuildingType          string
+	AC_COP                float64
+	EffectiveCOP          float64 // AC_COP at the part-load fraction of SystemType
+	SHGC                  float64
+	WWR                   float64
+	TransmissionFactor    float64
+	TimeLagFactor         float64
+	MedicalEquipFactor    float64
+	ElectricityCost       float64
+	ElectricityCostSource string
+	OperatingHours        float64
+	OperatingDays         float64
 }
 
 type Result struct {
-	Assumptions         Assumptions
+	Assumptions Assumptions
+
+	// Mode is "cooling", or "heating" when --heating-mode prices added
+	// solar gain. In heating mode CoolingLoadReduced and ElectricitySaved
+	// hold the heating load and heating energy saved.
+	Mode string
+
 	TotalSolarReduction float64
 	CoolingLoadReduced  float64
-	ElectricitySaved    float64
-	AnnualCostSaved     float64
+	// SolarLoadReduced and ConductionLoadReduced split CoolingLoadReduced
+	// when --u-factor adds conduction; ConductionLoadReduced is 0 without.
+	SolarLoadReduced       float64
+	ConductionLoadReduced  float64
+	ElectricitySaved       float64
+	CompressorSaved        float64 // kWh/day at the compressor alone, 0 without --system-losses
+	GridElectricitySaved   float64 // kWh/day no longer bought
+	SelfConsumptionSaved   float64 // kWh/day of PV output freed
+	OperatingFactor        float64
+	OperatingDaysPerYear   int     // from the calendar, 0 when none
+	TimeLagHours           float64 // from --thermal-mass, 0 when the scalar factor is used
+	PeakCoolingReduced     float64 // kW thermal
+	PeakElectricityReduced float64 // kW electric
+	PeakTonsReduced        float64
+	AnnualCostSaved        float64
+	CoincidentDemand       float64 // kW electric shaved at the utility peak, with --dr-incentive
+	DRRevenue              float64 // $/year demand response incentive, not part of the savings
+	HeatingPenalty         float64 // $/year of winter gain lost, 0 without --heating-season-fraction
+	NetAnnualSavings       float64 // window and roof savings less the heating penalty
+	PercentOfBill          float64 // net savings over --annual-bill, 0 when unset
+	ShapedRate             float64 // $/kWh time-weighted by --load-shape, 0 without one
+	PaybackYears           float64 // --project-cost over the savings; 0 when unset, +Inf without savings
+	// Intensity is the savings per m² of glazing and of --floor-area,
+	// nil when neither area is known.
+	Intensity *SavingsIntensity
+
+	// GridCO2 is the emission rate in kg CO2e/kWh used for CO2Avoided
+	// (kg/day) and AnnualCO2Avoided (kg/year) of the windows and any cool
+	// roof, on the EmissionsBasis chosen; GridCO2Source says where it
+	// came from.
+	EmissionsBasis   string
+	GridCO2          float64
+	GridCO2Source    string
+	CO2Avoided       float64
+	AnnualCO2Avoided float64
+	// PercentEmissionsReduced is AnnualCO2Avoided over
+	// --baseline-emissions, 0 when unset.
+	PercentEmissionsReduced float64
+	// CarbonValue is AnnualCO2Avoided priced at --carbon-price in
+	// $/year, and TotalAnnualBenefit adds it to NetAnnualSavings; both
+	// are 0 without a carbon price.
+	CarbonValue        float64
+	TotalAnnualBenefit float64
+
+	Roof         *RoofResult
+	CodeBaseline *CodeBaselineResult
+	Baseline     *BaselineDelta // --baseline-config comparison, nil without one
+	Sizing       *SizingResult
+	WindowGroups []WindowGroupResult
+	Monthly      []MonthSavings // by --monthly-cdd, nil without it
+
+	// DaysDelayed and ForegoneSavings are set when a start date is given:
+	// the days since the retrofit could have started and the savings
+	// missed over them.
+	DaysDelayed     int
+	ForegoneSavings float64
+
+	// Confidence maps each calculation input to measured, estimated or
+	// default.
+	Confidence map[string]string
+}
+
+const (
+	// kWPerTon converts tons of refrigeration (12,000 BTU/h) to kW.
+	kWPerTon = 3.517
+
+	// solarGainHours is the span of the day over which most solar gain
+	// arrives, so the daily load is concentrated into at most this many
+	// hours when deriving the peak.
+	solarGainHours = 8.0
+)
+
+// peakHours is the number of hours the daily solar cooling load is spread
+// over: the operating day, capped at the hours of significant solar gain.
+func peakHours(config Config) float64 {
+	return math.Min(config.OperatingHours, solarGainHours)
 }
 
-type ResultOutput struct {
-	// metadata
-	Timestamp    string `json:"timestamp"`
-	Location     string `json:"location"`
-	BuildingType string `json:"building_type"`
-
-	// inputs
-	SolarReduction     float64 `json:"solar_reduction_kwh_day"`
-	ElectricityCost    float64 `json:"electricity_cost_per_kwh"`
-	AC_COP             float64 `json:"ac_cop"`
-	SHGC               float64 `json:"shgc"`
-	WWR                float64 `json:"wwr"`
-	TransmissionFactor float64 `json:"transmission_factor"`
-	TimeLagFactor      float64 `json:"time_lag_factor"`
-	MedicalEquipFactor float64 `json:"medical_equip_factor"`
-
-	// results
-	CoolingLoadReduced float64 `json:"cooling_load_reduced_kwh_day"`
-	ElectricitySaved   float64 `json:"electricity_saved_kwh_day"`
-	DailyCostSaved     float64 `json:"daily_cost_saved_usd"`
+// peakPower converts a daily energy in kWh to the peak power in kW: the
+// mean over peakHours scaled by the load shape's peak-to-mean ratio.
+func peakPower(dailyEnergy float64, config Config) float64 {
+	return dailyEnergy / peakHours(config) * config.LoadShapeFactor
 }
 
-type Config struct {
-	Location           string
-	OutputDir          string
-	SolarReduction     float64
-	ElectricityCost    float64
-	AC_COP             float64
-	SHGC               float64
-	WWR                float64
-	TransmissionFactor float64
-	TimeLagFactor      float64
-	MedicalEquipFactor float64
+// gridFraction is the share of saved electricity that would have been
+// bought from the grid. With rooftop PV covering part of the cooling load,
+// the rest was self-generated and displaces no purchase.
+func gridFraction(config Config) float64 {
+	return 1 - config.PVOffsetFraction
 }
 
-func DefaultConfig() Config {
-	return Config{
-		Location:           "Sacramento",
-		AC_COP:             4.0,  // ASHRAE 90.1-2019
-		SHGC:               0.25, // CA Title 24 2022
-		WWR:                0.40, // DOE Reference Building
-		TransmissionFactor: 0.80,
-		TimeLagFactor:      0.95,
-		MedicalEquipFactor: 1.15,
-		OutputDir:          "results",
+// systemCOP is the cooling delivered per unit of electricity for the
+// whole HVAC system: the compressor's COP at part load with the fan and
+// pump parasitics, SystemLosses as a fraction of compressor power, added
+// on.
+func systemCOP(config Config) float64 {
+	return effectiveCOP(config) / (1 + config.SystemLosses)
+}
+
+// compressorSaved is the compressor's share of the electricity saved,
+// reported only when system losses make it differ from the total.
+func compressorSaved(config Config, coolingLoad float64) float64 {
+	if config.SystemLosses <= 0 {
+		return 0
 	}
+	return coolingLoad / effectiveCOP(config)
+}
+
+// scheduleFactor is the fraction of the year the building is conditioned.
+// Savings only accrue while it is in operation.
+func scheduleFactor(config Config) float64 {
+	return (config.OperatingHours / 24) * (config.OperatingDays / 7)
 }
 
 func calculateCoolingSavings(config Config) Result {
-	coolingLoadReduced := config.SolarReduction *
+	if config.HeatingMode {
+		return calculateHeatingSavings(config)
+	}
+
+	lagFactor, peakDecrement, lagHours := timeLag(config)
+	equip := equipFactor(config)
+	solarLoadReduced := config.SolarReduction *
 		config.SHGC *
 		config.TransmissionFactor *
-		config.TimeLagFactor *
-		config.MedicalEquipFactor
+		lagFactor *
+		equip
+	conduction := conductionLoad(config)
+	coolingLoadReduced := solarLoadReduced + conduction
+
+	cop := systemCOP(config)
+	electricitySaved := coolingLoadReduced / cop
+	peakCoolingReduced := peakPower(solarLoadReduced, config)*peakDecrement + conductionPower(config)
+
+	operatingFactor := scheduleFactor(config)
+	gridElectricitySaved := electricitySaved * gridFraction(config)
+	rate := config.ElectricityCost
+	if len(config.LoadShapeValues) == halfHours {
+		// The shape replaces the flat peak-to-mean ratio and weights the
+		// TOU rates by when the saving occurs.
+		rates, _ := touRates(config.TOUPeriods, config.ElectricityCost)
+		rate = shapedRate(config.LoadShapeValues, rates)
+		peakCoolingReduced = shapedPeak(solarLoadReduced, config.LoadShapeValues)*peakDecrement + conductionPower(config)
+	}
+	annualCostSaved := gridElectricitySaved * rate * 365 * operatingFactor
 
-	electricitySaved := coolingLoadReduced / config.AC_COP
-	annualCostSaved := electricitySaved * config.ElectricityCost * 365
+	costSource := config.CostSource
+	if costSource == "" {
+		costSource = "input"
+	}
 
 	return Result{
-		TotalSolarReduction: config.SolarReduction,
-		CoolingLoadReduced:  coolingLoadReduced,
-		ElectricitySaved:    electricitySaved,
-		AnnualCostSaved:     annualCostSaved,
+		Mode:                   "cooling",
+		TotalSolarReduction:    config.SolarReduction,
+		CoolingLoadReduced:     coolingLoadReduced,
+		SolarLoadReduced:       solarLoadReduced,
+		ConductionLoadReduced:  conduction,
+		ElectricitySaved:       electricitySaved,
+		CompressorSaved:        compressorSaved(config, coolingLoadReduced),
+		GridElectricitySaved:   gridElectricitySaved,
+		SelfConsumptionSaved:   electricitySaved - gridElectricitySaved,
+		OperatingFactor:        operatingFactor,
+		OperatingDaysPerYear:   config.OperatingDaysPerYear,
+		TimeLagHours:           lagHours,
+		PeakCoolingReduced:     peakCoolingReduced,
+		PeakElectricityReduced: peakCoolingReduced / cop,
+		PeakTonsReduced:        peakCoolingReduced / kWPerTon,
+		AnnualCostSaved:        annualCostSaved,
+		ShapedRate:             shapedRateOf(config, rate),
 		Assumptions: Assumptions{
-			Location:           config.Location,
-			BuildingType:       "Medical Clinic",
-			AC_COP:             config.AC_COP,
-			SHGC:               config.SHGC,
-			WWR:                config.WWR,
-			TransmissionFactor: config.TransmissionFactor,
-			TimeLagFactor:      config.TimeLagFactor,
-			MedicalEquipFactor: config.MedicalEquipFactor,
-			ElectricityCost:    config.ElectricityCost,
+			Location:              config.Location,
+			BuildingType:          "Medical Clinic",
+			AC_COP:                config.AC_COP,
+			EffectiveCOP:          effectiveCOP(config),
+			SHGC:                  config.SHGC,
+			WWR:                   config.WWR,
+			TransmissionFactor:    config.TransmissionFactor,
+			TimeLagFactor:         lagFactor,
+			MedicalEquipFactor:    equip,
+			ElectricityCost:       config.ElectricityCost,
+			ElectricityCostSource: costSource,
+			OperatingHours:        config.OperatingHours,
+			OperatingDays:         config.OperatingDays,
 			Units: Units{
-				SolarRadiation: "kWh/day",
-				CoolingLoad:    "kWh/day",
-				Electricity:    "kWh/day",
-				Cost:           "$/kWh",
-				Savings:        "$/year",
+				SolarRadiation:  "kWh/day",
+				CoolingLoad:     "kWh thermal/day",
+				Electricity:     "kWh electric/day",
+				PeakCooling:     "kW thermal",
+				PeakElectricity: "kW electric",
+				Cost:            "$/kWh",
+				Savings:         "$/year",
 			},
 		},
 	}
 }
 
-func saveResults(result Result, config Config) error {
-	if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
+// estimateInputs fills in a missing reduction from window groups or from
+// irradiance and glazed area, the operating days from a calendar, and a
+// missing cost from the state price table, by --state or else by the state
+// of a --location that sources shows was given, noting each estimate on out.
+func estimateInputs(config *Config, sources map[string]string, solar func() (SolarResource, error), out io.Writer) error {
+	if len(config.WindowGroups) > 0 {
+		if config.SolarReduction > 0 {
+			return errors.New("window_groups cannot be combined with --reduction")
+		}
+		if errs := validateWindowGroups(config.WindowGroups); len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		resource, err := solar()
+		if err != nil {
+			return err
+		}
+		applyWindowGroups(config, resource.AnnualGHI)
+		fmt.Fprintf(out, "Solar reduction from %d window groups: %.2f kWh/day (effective SHGC %.3f)\n",
+			len(config.WindowGroups), config.SolarReduction, config.SHGC)
 	}
 
-	timestamp := time.Now().Format("2006-01-02_150405")
-
-	output := ResultOutput{
-		Timestamp:          time.Now().Format(time.RFC3339),
-		Location:           result.Assumptions.Location,
-		BuildingType:       result.Assumptions.BuildingType,
-		SolarReduction:     result.TotalSolarReduction,
-		ElectricityCost:    result.Assumptions.ElectricityCost,
-		AC_COP:             result.Assumptions.AC_COP,
-		SHGC:               result.Assumptions.SHGC,
-		WWR:                result.Assumptions.WWR,
-		TransmissionFactor: result.Assumptions.TransmissionFactor,
-		TimeLagFactor:      result.Assumptions.TimeLagFactor,
-		MedicalEquipFactor: result.Assumptions.MedicalEquipFactor,
-		CoolingLoadReduced: result.CoolingLoadReduced,
-		ElectricitySaved:   result.ElectricitySaved,
-		DailyCostSaved:     result.AnnualCostSaved,
+	if config.EPlusCSV != "" {
+		if config.SolarReduction != 0 || len(config.WindowGroups) > 0 {
+			return errors.New("--eplus-csv replaces --reduction and window_groups; give only one")
+		}
+		imported, err := importEPlus(*config)
+		if err != nil {
+			return err
+		}
+		config.SolarReduction = imported.Daily()
+		if config.HeatingMode {
+			config.SolarReduction = -config.SolarReduction
+		}
+		fmt.Fprintf(out, "Solar reduction from EnergyPlus: %.2f kWh/day (%.0f kWh over %d days of %q)\n",
+			config.SolarReduction, imported.Total, imported.Days, imported.Column)
 	}
 
-	jsonPath := filepath.Join(config.OutputDir, fmt.Sprintf("solar_cooling_%s.json", timestamp))
-	jsonData, err := json.MarshalIndent(output, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %v", err)
+	if config.WindowArea == 0 && config.WallArea > 0 && len(config.WindowGroups) == 0 {
+		// The WWR only enters the model through the glazed area it
+		// implies over the wall.
+		config.WindowArea = config.WWR * config.WallArea
+		fmt.Fprintf(out, "Window area: %.1f m² (WWR %.2f x %.1f m² of wall)\n",
+			config.WindowArea, config.WWR, config.WallArea)
 	}
-	if err := os.WriteFile(jsonPath, jsonData, 0o644); err != nil {
-		return fmt.Errorf("failed to write JSON file: %v", err)
+
+	if config.Film != "" {
+		if err := applyFilm(config, out); err != nil {
+			return err
+		}
 	}
 
-	csvPath := filepath.Join(config.OutputDir, fmt.Sprintf("solar_cooling_%s.csv", timestamp))
-	csvFile, err := os.Create(csvPath)
-	if err != nil {
-		return fmt.Errorf("failed to create CSV file: %v", err)
+	if config.SHGCExisting > 0 || config.SHGCProposed > 0 {
+		if err := checkRetrofitSHGC(*config); err != nil {
+			return err
+		}
+		resource, err := solar()
+		if err != nil {
+			return err
+		}
+		applyRetrofitSHGC(config, resource.AnnualGHI)
+		fmt.Fprintf(out, "Solar reduction from SHGC %.2f -> %.2f: %.2f kWh/day (%.0f%% of the %.2f kWh/day on %.1f m²)\n",
+			config.SHGCExisting, config.SHGCProposed, config.SolarReduction,
+			100*retrofitFraction(*config), resource.AnnualGHI*config.WindowArea, config.WindowArea)
 	}
-	defer csvFile.Close()
 
-	writer := csv.NewWriter(csvFile)
-	defer writer.Flush()
+	if config.SolarReduction == 0 && config.WindowArea > 0 {
+		resource, err := solar()
+		if err != nil {
+			return err
+		}
+		config.SolarReduction = resource.AnnualGHI * config.WindowArea
+		if config.HeatingMode {
+			// In heating mode the glazing admits this gain rather than
+			// blocking it.
+			config.SolarReduction = -config.SolarReduction
+		}
+		fmt.Fprintf(out, "Estimated solar reduction: %.2f kWh/day (%.2f kWh/m²/day from %s x %.1f m²)\n",
+			config.SolarReduction, resource.AnnualGHI, resource.Source, config.WindowArea)
+	}
 
-	headers := []string{
-		"Timestamp", "Location", "Building Type",
-		"Solar Reduction (kWh/day)", "Electricity Cost ($/kWh)",
-		"AC COP", "SHGC", "WWR",
-		"Transmission Factor", "Time Lag Factor", "Medical Equipment Factor",
-		"Cooling Load Reduced (kWh/day)", "Electricity Saved (kWh/day)",
-		"Daily Cost Saved ($)",
+	if config.LoadShape != "" {
+		if err := applyLoadShape(config); err != nil {
+			return err
+		}
 	}
 
-	data := []string{
-		output.Timestamp, output.Location, output.BuildingType,
-		fmt.Sprintf("%.2f", output.SolarReduction),
-		fmt.Sprintf("%.3f", output.ElectricityCost),
-		fmt.Sprintf("%.1f", output.AC_COP),
-		fmt.Sprintf("%.2f", output.SHGC),
-		fmt.Sprintf("%.2f", output.WWR),
-		fmt.Sprintf("%.2f", output.TransmissionFactor),
-		fmt.Sprintf("%.2f", output.TimeLagFactor),
-		fmt.Sprintf("%.2f", output.MedicalEquipFactor),
-		fmt.Sprintf("%.2f", output.CoolingLoadReduced),
-		fmt.Sprintf("%.2f", output.ElectricitySaved),
-		fmt.Sprintf("%.2f", output.DailyCostSaved),
+	if config.MonthlyCDD != "" {
+		if err := applyMonthlyCDD(config); err != nil {
+			return err
+		}
 	}
 
-	if err := writer.Write(headers); err != nil {
-		return fmt.Errorf("failed to write CSV headers: %v", err)
+	if config.Calendar != "" || config.Holidays != "" {
+		days, err := applyCalendar(config)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Operating calendar: %d open days in %d (%.2f days/week)\n",
+			days, config.CalendarYear, config.OperatingDays)
 	}
-	if err := writer.Write(data); err != nil {
-		return fmt.Errorf("failed to write CSV data: %v", err)
+
+	if config.ElectricityCost <= 0 && config.State != "" {
+		ttl, err := config.cacheTTL()
+		if err != nil {
+			return err
+		}
+		estimate, warning, err := resolveStatePrice(config.EIAAPIKey, config.State, ttl)
+		if warning != "" {
+			fmt.Fprintf(warnings, "Warning: %s\n", warning)
+		}
+		if err != nil {
+			return err
+		}
+		config.ElectricityCost = estimate.Price
+		config.CostSource = fmt.Sprintf("estimate: %s %s commercial average, %s",
+			estimate.Source, strings.ToUpper(config.State), estimate.Period)
+		if estimate.CacheAge > 0 {
+			config.CostSource += ", from cache " + cacheAge(estimate.CacheAge)
+		}
+		if estimate.CacheAge > 0 && warning == "" {
+			fmt.Fprintf(out, "Note: %s electricity price read from cache, %s (--cache-ttl %s)\n",
+				estimate.Source, cacheAge(estimate.CacheAge), config.CacheTTL)
+		}
+	} else if config.ElectricityCost <= 0 && config.CostHook == "" && !config.HeatingMode && sources["location"] != "default" {
+		// Without --cost or --state a known location still implies a
+		// regional rate; an unknown one, or the default Sacramento, leaves
+		// the cost missing.
+		if data, ok := lookupLocation(config.Location); ok {
+			if price, ok := statePrices[data.State]; ok {
+				config.ElectricityCost = price
+				config.CostSource = fmt.Sprintf("default: bundled %s commercial average for %s, 2023",
+					data.State, config.Location)
+				fmt.Fprintf(out, "No --cost given; using the %s default of $%.3f/kWh\n", config.Location, price)
+			}
+		}
 	}
 
 	return nil
 }
 
-func main() {
-	config := DefaultConfig()
+// computeResult runs config's window model, prices it with the cost hook
+// when one is configured, adds the advisory equipment sizing, splits it
+// across any window groups and, when a roof area is set, adds the
+// cool-roof component.
+func computeResult(config Config, solar func() (SolarResource, error)) (Result, error) {
+	result := modelOf(config).Compute(config)
+	// Heating-season days pay the heating penalty instead.
+	season := coolingSeason(config)
+	result.AnnualCostSaved *= season
+
+	if config.CostHook != "" {
+		annual, err := runCostHook(config.CostHook, CostHookInput{
+			Location:                config.Location,
+			ElectricitySavedDaily:   result.ElectricitySaved,
+			ElectricitySavedAnnual:  result.ElectricitySaved * 365 * result.OperatingFactor * season,
+			PeakElectricityReduced:  result.PeakElectricityReduced,
+			OperatingHours:          config.OperatingHours,
+			OperatingDays:           config.OperatingDays,
+			ElectricityCostFallback: config.ElectricityCost,
+			PVOffsetFraction:        config.PVOffsetFraction,
+		})
+		if err != nil {
+			return Result{}, err
+		}
+		result.AnnualCostSaved = annual
+		result.Assumptions.ElectricityCostSource = "cost hook: " + config.CostHook
+	}
 
-	var (
-		verbose     bool
-		showVersion bool
-	)
+	result.Sizing = equipmentSizing(result, config)
+	if config.DRIncentive > 0 {
+		// Demand response pays per kW shaved at the utility's peak, which
+		// only part of the building's own peak reduction may coincide with.
+		result.CoincidentDemand = result.PeakElectricityReduced * config.CoincidenceFactor
+		result.DRRevenue = result.CoincidentDemand * config.DRIncentive
+	}
 
-	const version = "1.4.0"
+	if config.RoofArea > 0 {
+		resource, err := solar()
+		if err != nil {
+			return Result{}, err
+		}
+		result.Roof = calculateRoofSavings(config, resource.AnnualGHI)
+	}
+	applyEmissions(&result, config)
+	if config.DiffBaseline {
+		result.CodeBaseline = codeBaseline(result, config)
+	}
 
-	pflag.Float64VarP(&config.SolarReduction, "reduction", "r", 0.0,
-		"Total solar radiation reduction in kWh/day")
-	pflag.Float64VarP(&config.ElectricityCost, "cost", "c", 0.0,
-		"Electricity cost in $/kWh")
+	saved := result.AnnualCostSaved
+	if result.Roof != nil {
+		saved += result.Roof.AnnualCostSaved
+	}
+	result.HeatingPenalty = heatingPenalty(config, result.OperatingFactor)
+	saved -= result.HeatingPenalty
+	result.NetAnnualSavings = saved
+	if result.CarbonValue > 0 {
+		result.TotalAnnualBenefit = saved + result.CarbonValue
+	}
+	if config.AnnualBill > 0 {
+		result.PercentOfBill = 100 * saved / config.AnnualBill
+	}
+	if config.ProjectCost > 0 {
+		result.PaybackYears = math.Inf(1)
+		if saved > 0 {
+			result.PaybackYears = config.ProjectCost / saved
+		}
+	}
+	result.Intensity = calculateSavingsIntensity(result, config)
+
+	if len(config.MonthlyCDDValues) == monthsPerYear {
+		result.Monthly = monthlySavings(result, config.MonthlyCDDValues)
+	}
+
+	if config.StartDate != "" {
+		start, err := time.Parse(time.DateOnly, config.StartDate)
+		if err != nil {
+			return Result{}, err
+		}
+		result.DaysDelayed = int(outputTime(config).Sub(start).Hours() / 24)
+		result.ForegoneSavings = result.AnnualCostSaved / 365 * float64(result.DaysDelayed)
+	}
+
+	if len(config.WindowGroups) > 0 {
+		resource, err := solar()
+		if err != nil {
+			return Result{}, err
+		}
+		result.WindowGroups = windowGroupResults(config, resource.AnnualGHI, result)
+	}
 
-	pflag.StringVarP(&config.Location, "location", "l", config.Location,
-		"Building location")
-	pflag.Float64Var(&config.AC_COP, "cop", config.AC_COP,
-		"Air conditioning Coefficient of Performance")
-	pflag.Float64Var(&config.SHGC, "shgc", config.SHGC,
-		"Solar Heat Gain Coefficient")
-	pflag.Float64Var(&config.WWR, "wwr", config.WWR,
-		"Window to Wall Ratio")
-	pflag.StringVarP(&config.OutputDir, "output", "o", config.OutputDir,
-		"Output directory for CSV and JSON files")
-
-	pflag.BoolVarP(&verbose, "verbose", "v", false,
-		"Show detailed assumptions and calculations")
-	pflag.BoolVarP(&showVersion, "version", "V", false,
-		"Show program version")
-
-	pflag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Solar Cooling Energy Calculator for Medical Clinics v%s\n\n", version)
-		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  calculator [flags]\n\n")
-		fmt.Fprintf(os.Stderr, "Required Flags:\n")
-		fmt.Fprintf(os.Stderr, "  -r, --reduction float   Total solar radiation reduction in kWh/day\n")
-		fmt.Fprintf(os.Stderr, "  -c, --cost float        Electricity cost in $/kWh\n\n")
-		fmt.Fprintf(os.Stderr, "Optional Flags (with defaults):\n")
-		fmt.Fprintf(os.Stderr, "      --cop float         AC Coefficient of Performance (default: %.1f)\n", config.AC_COP)
-		fmt.Fprintf(os.Stderr, "      --shgc float        Solar Heat Gain Coefficient (default: %.2f)\n", config.SHGC)
-		fmt.Fprintf(os.Stderr, "      --wwr float         Window to Wall Ratio (default: %.2f)\n", config.WWR)
-		fmt.Fprintf(os.Stderr, "  -l, --location string   Building location (default: %s)\n", config.Location)
-		fmt.Fprintf(os.Stderr, "  -o, --output string     Output directory (default: %s)\n\n", config.OutputDir)
-		fmt.Fprintf(os.Stderr, "Other Options:\n")
-		fmt.Fprintf(os.Stderr, "  -v, --verbose          Show detailed assumptions and calculations\n")
-		fmt.Fprintf(os.Stderr, "  -V, --version          Show program version\n\n")
-		fmt.Fprintf(os.Stderr, "Examples:\n")
-		fmt.Fprintf(os.Stderr, "  calculator -r 100 -c 0.15\n")
-		fmt.Fprintf(os.Stderr, "  calculator --reduction 150.5 --cost 0.12 --cop 3.5 --shgc 0.3 -o results\n")
-	}
-
-	pflag.Parse()
-
-	if showVersion {
-		fmt.Printf("Solar Cooling Energy Calculator v%s\n", version)
-		os.Exit(0)
-	}
-
-	if config.SolarReduction <= 0 {
-		fmt.Println("Error: Solar reduction must be a positive number")
-		pflag.Usage()
-		os.Exit(1)
-	}
-
-	if config.ElectricityCost <= 0 {
-		fmt.Println("Error: Electricity cost must be a positive number")
-		pflag.Usage()
-		os.Exit(1)
-	}
-
-	if config.SHGC <= 0 || config.SHGC > 1 {
-		fmt.Println("Error: SHGC must be between 0 and 1")
-		os.Exit(1)
-	}
-
-	if config.WWR <= 0 || config.WWR > 1 {
-		fmt.Println("Error: WWR must be between 0 and 1")
-		os.Exit(1)
-	}
-
-	if config.AC_COP <= 0 {
-		fmt.Println("Error: COP must be positive")
-		os.Exit(1)
-	}
-
-	result := calculateCoolingSavings(config)
-
-	if err := saveResults(result, config); err != nil {
-		fmt.Printf("Error saving results: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Printf("\nCalculation Results (Daily):\n")
-	fmt.Printf("Location: %s\n", result.Assumptions.Location)
-	fmt.Printf("Building type: %s\n", result.Assumptions.BuildingType)
-
-	fmt.Printf("\nInputs:\n")
-	fmt.Printf("Total solar radiation reduction: %.2f %s\n",
-		result.TotalSolarReduction,
-		result.Assumptions.Units.SolarRadiation)
-	fmt.Printf("Electricity cost: %.3f %s\n",
-		result.Assumptions.ElectricityCost,
-		result.Assumptions.Units.Cost)
-
-	if verbose {
-		fmt.Printf("AC COP: %.1f\n", result.Assumptions.AC_COP)
-		fmt.Printf("Solar Heat Gain Coefficient: %.2f\n", result.Assumptions.SHGC)
-		fmt.Printf("Window-to-Wall Ratio: %.2f\n", result.Assumptions.WWR)
-	}
-
-	fmt.Printf("\nResults:\n")
-	fmt.Printf("Total cooling load reduced: %.2f %s\n",
-		result.CoolingLoadReduced,
-		result.Assumptions.Units.CoolingLoad)
-	fmt.Printf("Total electricity saved: %.2f %s\n",
-		result.ElectricitySaved,
-		result.Assumptions.Units.Electricity)
-	fmt.Printf("Annual cost savings: %.2f %s\n",
-		result.AnnualCostSaved,
-		result.Assumptions.Units.Savings)
+	return result, nil
+}
 
-	if verbose {
-		fmt.Printf("\nDetailed Assumptions:\n")
-		fmt.Printf("Transmission Factor: %.2f\n", result.Assumptions.TransmissionFactor)
-		fmt.Printf("Time Lag Factor: %.2f\n", result.Assumptions.TimeLagFactor)
-		fmt.Printf("Medical Equipment Factor: %.2f\n", result.Assumptions.MedicalEquipFactor)
+// runScenario estimates any missing inputs, validates and computes one
+// scenario without writing files. It returns the result together with the
+// config after estimation.
+func runScenario(config Config, sources map[string]string, out io.Writer) (Result, Config, error) {
+	solar := newSolarLookup(&config, warnings, out)
+
+	if err := estimateInputs(&config, sources, solar, out); err != nil {
+		return Result{}, config, err
+	}
+	if err := config.Validate(); err != nil {
+		return Result{}, config, err
+	}
+	printWarnings(warnings, config)
+	result, err := computeResult(config, solar)
+	if err != nil {
+		return Result{}, config, err
+	}
+	result.Confidence = inputConfidence(config, sources)
+	if err := audit.record(result, config); err != nil {
+		return Result{}, config, err
+	}
+	return result, config, nil
+}
+
+// printErrors writes each error joined into err on its own line.
+func printErrors(err error) {
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Printf("Error: %s\n", line)
+	}
+}
+
+// version is reported by --version and in usage text.
+const version = "1.4.0"
+
+// command is one calculator subcommand. run receives the arguments after
+// the command name and returns the process exit code.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+// commands is filled in by init, since calc's usage text lists it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"calc", "Calculate savings for one building (the default)", runCalc},
+		{"batch", "Calculate one scenario per row of a CSV file", runBatchCommand},
+		{"serve", "Serve calculations over HTTP", runServe},
+		{"schema", "Print the JSON Schema of the config or result files", runSchema},
+		{"validate", "Check a configuration without calculating", runValidate},
+		{"selftest", "Check the calculation against known results", runSelftest},
+		{"init", "Write a commented config file with the defaults", runInit},
+	}
+}
+
+// printCommands lists the subcommands for usage text.
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'calculator <command> --help' for the flags of each command.\n\n")
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		for _, cmd := range commands {
+			if args[0] == cmd.name {
+				os.Exit(cmd.run(args[1:]))
+			}
+		}
 	}
+	// Without a command name the arguments are calc flags, as they were
+	// before subcommands existed.
+	os.Exit(runCalc(args))
 }
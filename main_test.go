@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMissingCostFallsBackToTheLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		given    bool // whether the location was given or left at its default
+		cost     float64
+		source   string
+	}{
+		{"Phoenix", "Phoenix", true, 0.120, "default: bundled AZ commercial average for Phoenix, 2023"},
+		{"Sacramento given", "sacramento", true, 0.245, "default: bundled CA commercial average for sacramento, 2023"},
+		{"Sacramento by default", "Sacramento", false, 0, ""},
+		{"unknown", "Nowhere", true, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultConfigWith(func(c *Config) { c.SolarReduction, c.Location = 100, tt.location })
+			sources := map[string]string{"location": "default"}
+			if tt.given {
+				sources["location"] = "flag"
+			}
+			if err := estimateInputs(&config, sources, nil, io.Discard); err != nil {
+				t.Fatal(err)
+			}
+			if config.ElectricityCost != tt.cost || config.CostSource != tt.source {
+				t.Errorf("cost %g from %q, want %g from %q", config.ElectricityCost, config.CostSource, tt.cost, tt.source)
+			}
+			err := config.Validate()
+			if tt.cost > 0 && err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+			if tt.cost == 0 && (err == nil || !strings.Contains(err.Error(), "known --location")) {
+				t.Errorf("Validate = %v, want the missing cost error", err)
+			}
+		})
+	}
+}
+
+func TestWWRSetsTheWindowAreaOverAWall(t *testing.T) {
+	solar := func() (SolarResource, error) { return SolarResource{AnnualGHI: 5.1, Source: "test"}, nil }
+	tests := []struct {
+		name       string
+		config     func(c *Config)
+		windowArea float64
+		reduction  float64
+		warning    string // in Config.Warnings, "" for none
+	}{
+		{"reduction given", func(c *Config) { c.SolarReduction = 100 }, 0, 100, ""},
+		{"reduction given, WWR changed", func(c *Config) { c.SolarReduction, c.WWR = 100, 0.2 },
+			0, 100, "WWR 0.20 does not change the result without --wall-area"},
+		{"wall at WWR 0.2", func(c *Config) { c.WallArea, c.WWR = 100, 0.2 }, 20, 5.1 * 20, ""},
+		{"wall at WWR 0.4", func(c *Config) { c.WallArea, c.WWR = 100, 0.4 }, 40, 5.1 * 40, ""},
+		{"window area wins over the WWR", func(c *Config) { c.WallArea, c.WindowArea, c.WWR = 100, 30, 0.4 },
+			30, 5.1 * 30, "Window area 30.0 m² is 25% off the 40.0 m² implied by WWR 0.40"},
+		{"window area within tolerance", func(c *Config) { c.WallArea, c.WindowArea, c.WWR = 100, 36, 0.4 },
+			36, 5.1 * 36, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultConfigWith(func(c *Config) { c.ElectricityCost = 0.15; tt.config(c) })
+			if err := estimateInputs(&config, nil, solar, io.Discard); err != nil {
+				t.Fatal(err)
+			}
+			if config.WindowArea != tt.windowArea || math.Abs(config.SolarReduction-tt.reduction) > 1e-9 {
+				t.Errorf("window area %g and reduction %g, want %g and %g",
+					config.WindowArea, config.SolarReduction, tt.windowArea, tt.reduction)
+			}
+			warnings := strings.Join(config.Warnings(), "\n")
+			if (warnings == "") != (tt.warning == "") || !strings.Contains(warnings, tt.warning) {
+				t.Errorf("warnings %q, want %q", warnings, tt.warning)
+			}
+			result, err := computeResult(config, solar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The savings follow the reduction alone, whatever the WWR.
+			want := calculateCoolingSavings(defaultConfigWith(func(c *Config) {
+				c.SolarReduction, c.ElectricityCost = tt.reduction, 0.15
+			})).AnnualCostSaved
+			if math.Abs(result.AnnualCostSaved-want) > 1e-9 {
+				t.Errorf("AnnualCostSaved = %g, want %g", result.AnnualCostSaved, want)
+			}
+		})
+	}
+}
+
+func TestStartDateCountsToTheOutputTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   string
+		delayed int
+		invalid bool
+	}{
+		{"a month before", "2023-12-02", 30, false},
+		{"on the day", "2024-01-01", 0, false},
+		{"after", "2024-06-01", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultConfigWith(func(c *Config) {
+				c.SolarReduction, c.ElectricityCost = 100, 0.15
+				c.StartDate, c.OutputTimestamp = tt.start, "2024-01-01T00:00:00Z"
+			})
+			err := config.Validate()
+			if tt.invalid {
+				if err == nil || !strings.Contains(err.Error(), "Start date cannot be in the future") {
+					t.Errorf("Validate = %v, want the future start date error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := computeResult(config, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := result.AnnualCostSaved / 365 * float64(tt.delayed); result.DaysDelayed != tt.delayed || result.ForegoneSavings != want {
+				t.Errorf("%d days delayed, $%g foregone; want %d and $%g",
+					result.DaysDelayed, result.ForegoneSavings, tt.delayed, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultReferences are the standards behind the DefaultConfig values,
+// for the --manifest. Keys without one are engineering judgement.
+var defaultReferences = map[string]string{
+	"ac_cop":                    "ASHRAE 90.1-2019",
+	"shgc":                      "CA Title 24 2022",
+	"wwr":                       "DOE Reference Building",
+	"load_shape_factor":         "flat load over the solar gain window",
+	"sizing_safety_factor":      "typical design-load margin",
+	"heating_cop":               "air-source heat pump, seasonal average",
+	"roof_absorptance":          "typical dark membrane",
+	"roof_absorptance_proposed": "CA Title 24 2022 aged cool roof",
+	"roof_u_factor":             "CA Title 24 2022 U-0.034",
+}
+
+// manifestEntry is one input of the assumptions manifest.
+type manifestEntry struct {
+	Key       string `json:"key"`
+	Flag      string `json:"flag"`
+	Value     any    `json:"value"`
+	Source    string `json:"source"` // flag, file:<path>, default or derived
+	Reference string `json:"reference,omitempty"`
+}
+
+// assumptionsManifest lists every input of config with where its value
+// came from and, for defaults and derived values, what it rests on.
+func assumptionsManifest(config Config, sources map[string]string, result Result) []manifestEntry {
+	values := configValues(config)
+	defaults := configValues(DefaultConfig())
+
+	entries := make([]manifestEntry, 0, len(configFields))
+	for _, field := range configFields {
+		entry := manifestEntry{Key: field.Key, Flag: field.Flag, Value: values[field.Key], Source: sources[field.Key]}
+		if entry.Source == "default" && entry.Value != defaults[field.Key] {
+			entry.Source = "derived"
+		}
+		switch {
+		case field.Key == "electricity_cost" && config.CostSource != "":
+			entry.Reference = config.CostSource
+		case field.Key == "grid_co2" && config.GridCO2 == 0:
+			entry.Value, entry.Source, entry.Reference = result.GridCO2, "derived", result.GridCO2Source
+		case entry.Source == "default":
+			entry.Reference = defaultReferences[field.Key]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// writeManifest writes the manifest to path as a Markdown table when it
+// ends in .md, and otherwise as JSON.
+func writeManifest(path string, entries []manifestEntry) error {
+	data, err := manifestData(path, entries)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// manifestData renders entries as a Markdown table when path ends in .md,
+// and as JSON otherwise.
+func manifestData(path string, entries []manifestEntry) ([]byte, error) {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		var b strings.Builder
+		b.WriteString("# Assumptions manifest\n\n")
+		b.WriteString("| Input | Flag | Value | Source | Reference |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, e := range entries {
+			value := strings.ReplaceAll(fmt.Sprint(e.Value), "|", `\|`)
+			fmt.Fprintf(&b, "| %s | --%s | %s | %s | %s |\n", e.Key, e.Flag, value, e.Source, e.Reference)
+		}
+		data = []byte(b.String())
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(entries, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to marshal manifest: %v", err)
+		}
+		data = append(data, '\n')
+	}
+	return data, nil
+}
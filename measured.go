@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// measuredDiscrepancy is the relative gap between the measured and the
+// modelled savings above which the measurement is flagged.
+const measuredDiscrepancy = 0.25
+
+// billMonth is one month of metered consumption before and after the
+// retrofit.
+type billMonth struct {
+	Month         string
+	Before, After float64 // kWh
+}
+
+// readBills reads month,before_kwh,after_kwh rows, skipping a header.
+func readBills(r io.Reader) ([]billMonth, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	var bills []billMonth
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(record) != 3 {
+			return nil, fmt.Errorf("line %d: expected month,before_kwh,after_kwh", line)
+		}
+		before, err1 := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		after, err2 := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err1 != nil || err2 != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid kWh", line)
+		}
+		if before < 0 || after < 0 {
+			return nil, fmt.Errorf("line %d: kWh cannot be negative", line)
+		}
+		bills = append(bills, billMonth{strings.TrimSpace(record[0]), before, after})
+	}
+	if len(bills) == 0 {
+		return nil, errors.New("no monthly readings")
+	}
+	if len(bills) > 12 {
+		return nil, fmt.Errorf("%d months given; compare at most one year before and after", len(bills))
+	}
+	return bills, nil
+}
+
+// MeasuredSavings compares metered bills with the model.
+type MeasuredSavings struct {
+	Months          int
+	AnnualKWhSaved  float64 // measured, annualized from the months given
+	AnnualCostSaved float64 // AnnualKWhSaved at the electricity cost
+	// ImpliedReduction is the solar reduction in kWh/day that the model
+	// needs to reproduce the measured saving with the other inputs.
+	ImpliedReduction float64
+	// ModelledKWhSaved is the forward model's annual saving, 0 when no
+	// reduction was given to model.
+	ModelledKWhSaved float64
+}
+
+// Discrepancy is the measured saving relative to the modelled, less one.
+func (m MeasuredSavings) Discrepancy() float64 {
+	return m.AnnualKWhSaved/m.ModelledKWhSaved - 1
+}
+
+// measureSavings annualizes the bill deltas and runs the model backwards:
+// the measured kWh, spread over the operating days, is the daily
+// electricity saved, which less any conduction savings the cooling chain
+// turns into a reduction.
+// No weather normalization is applied.
+func measureSavings(config Config, bills []billMonth) MeasuredSavings {
+	var saved float64
+	for _, b := range bills {
+		saved += b.Before - b.After
+	}
+	m := MeasuredSavings{Months: len(bills), AnnualKWhSaved: saved * 12 / float64(len(bills))}
+	m.AnnualCostSaved = m.AnnualKWhSaved * config.ElectricityCost
+
+	lagFactor, _, _ := timeLag(config)
+	perReduction := config.SHGC * config.TransmissionFactor * lagFactor * equipFactor(config) / systemCOP(config)
+	daily := m.AnnualKWhSaved/(365*scheduleFactor(config)) - conductionLoad(config)/systemCOP(config)
+	if perReduction > 0 {
+		m.ImpliedReduction = daily / perReduction
+	}
+	if config.SolarReduction > 0 {
+		modelled := modelOf(config).Compute(config)
+		m.ModelledKWhSaved = modelled.ElectricitySaved * 365 * modelled.OperatingFactor
+	}
+	return m
+}
+
+// printMeasuredSavings reports the measured savings and, when there is a
+// modelled saving, flags a gap over measuredDiscrepancy.
+func printMeasuredSavings(w io.Writer, m MeasuredSavings, config Config) {
+	fmt.Fprintf(w, "Measured savings over %d month(s), annualized:\n", m.Months)
+	fmt.Fprintf(w, "Electricity saved: %.0f kWh/year ($%.2f/year at $%.3f/kWh)\n",
+		m.AnnualKWhSaved, m.AnnualCostSaved, config.ElectricityCost)
+	fmt.Fprintf(w, "Implied solar reduction: %.2f kWh/day with the other inputs as given\n", m.ImpliedReduction)
+	if m.ModelledKWhSaved <= 0 {
+		fmt.Fprintln(w, "Give --reduction to cross-check against the forward model")
+		return
+	}
+	fmt.Fprintf(w, "Modelled: %.0f kWh/year from %.2f kWh/day; measured is %+.0f%%\n",
+		m.ModelledKWhSaved, config.SolarReduction, 100*m.Discrepancy())
+	if math.Abs(m.Discrepancy()) > measuredDiscrepancy {
+		fmt.Fprintf(w, "Warning: measured and modelled savings differ by more than %.0f%%; check the inputs, "+
+			"or other changes in the building and the weather between the two periods\n", 100*measuredDiscrepancy)
+	}
+}
+
+// loadBills reads the bills file at path.
+func loadBills(path string) ([]billMonth, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	bills, err := readBills(file)
+	if err != nil {
+		return nil, fmt.Errorf("bills %s: %v", path, err)
+	}
+	return bills, nil
+}
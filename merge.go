@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// expandInputs resolves each pattern with filepath.Glob, keeping plain
+// paths that match nothing so that the later read reports them missing.
+func expandInputs(patterns []string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			matches = []string{pattern}
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				paths = append(paths, m)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// readOutputFile reads a result file, decompressing it when it was
+// written with --gzip.
+func readOutputFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasSuffix(path, ".gz") {
+		return data, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// legacyOutput holds the version 1 fields that have since been renamed.
+type legacyOutput struct {
+	DailyCostSaved float64 `json:"daily_cost_saved_usd"`
+}
+
+// loadResultOutputs reads ResultOutput JSON files, writing a warning to
+// warn for every file whose schema version differs from this build's.
+// Version 1 files are upgraded, keeping their savings from the old key,
+// and files written with --json-with-units have their units dropped.
+func loadResultOutputs(paths []string, warn io.Writer) ([]ResultOutput, error) {
+	outputs := make([]ResultOutput, 0, len(paths))
+
+	for _, path := range paths {
+		data, err := readOutputFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if data, err = withoutUnits(data); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		var output ResultOutput
+		if err := json.Unmarshal(data, &output); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		if output.SchemaVersion != outputSchemaVersion {
+			fmt.Fprintf(warn, "Warning: %s has schema version %d, expected %d\n",
+				path, output.SchemaVersion, outputSchemaVersion)
+		}
+		if output.SchemaVersion == 1 {
+			var legacy legacyOutput
+			if err := json.Unmarshal(data, &legacy); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+			}
+			output.SchemaVersion, output.AnnualCostSaved = outputSchemaVersion, legacy.DailyCostSaved
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// mergeResults combines previously written result files into a single
+// JSON array and CSV in outputDir, encoded as format specifies, and
+// returns the paths written.
+func mergeResults(patterns []string, outputDir string, format outputFormat, warn io.Writer) (string, string, error) {
+	paths, err := expandInputs(patterns)
+	if err != nil {
+		return "", "", err
+	}
+
+	outputs, err := loadResultOutputs(paths, warn)
+	if err != nil {
+		return "", "", err
+	}
+
+	timestamp := fileTimestamp(format.Now())
+	return writeOutputFiles(outputDir, "solar_cooling_merged_"+timestamp, format, outputs, outputs)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeWarnsAboutOlderSchemaVersions(t *testing.T) {
+	config := defaultConfigWith(func(c *Config) { c.SolarReduction, c.ElectricityCost = 100, 0.15 })
+	output := resultOutputAt(calculateCoolingSavings(config), config, goldenTime)
+	var current bytes.Buffer
+	if err := renderJSON(&current, output, false); err != nil {
+		t.Fatal(err)
+	}
+	// A version 1 file, from before annual_cost_saved_usd was renamed.
+	old := strings.Replace(current.String(), `"schema_version": 2`, `"schema_version": 1`, 1)
+	old = strings.Replace(old, `"annual_cost_saved_usd"`, `"daily_cost_saved_usd"`, 1)
+
+	dir := t.TempDir()
+	v1, v2 := filepath.Join(dir, "v1.json"), filepath.Join(dir, "v2.json")
+	if err := os.WriteFile(v1, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(v2, current.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		paths []string
+		warns bool
+	}{
+		{"current only", []string{v2}, false},
+		{"version 1 only", []string{v1}, true},
+		{"version 1 first", []string{v1, v2}, true},
+		{"version 1 last", []string{v2, v1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warn bytes.Buffer
+			outputs, err := loadResultOutputs(tt.paths, &warn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "Warning: " + v1 + " has schema version 1, expected 2\n"
+			if tt.warns != (warn.String() == want) {
+				t.Errorf("warnings %q, want the version 1 warning: %v", warn.String(), tt.warns)
+			}
+			for i, got := range outputs {
+				if got.SchemaVersion != outputSchemaVersion || got.AnnualCostSaved != output.AnnualCostSaved {
+					t.Errorf("%s: version %d saving %g, want version %d saving %g", tt.paths[i],
+						got.SchemaVersion, got.AnnualCostSaved, outputSchemaVersion, output.AnnualCostSaved)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeReadsFilesWrittenWithUnits(t *testing.T) {
+	config := defaultConfigWith(func(c *Config) {
+		c.SolarReduction, c.ElectricityCost, c.DumpIntermediates = 100, 0.15, true
+		c.RoofArea, c.FloorArea, c.ProjectCost = 100, 500, 2500
+	})
+	result, err := computeResult(config, func() (SolarResource, error) {
+		return SolarResource{AnnualGHI: 5.1, Source: "test"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := resultOutputAt(result, config, goldenTime)
+	dir := t.TempDir()
+	var paths []string
+	for _, units := range []bool{false, true} {
+		format, err := outputFormatOf(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		format.WithUnits = units
+		name := map[bool]string{false: "plain", true: "units"}[units]
+		jsonPath, _, err := writeOutputFiles(dir, name, format, output, []ResultOutput{output})
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, jsonPath)
+	}
+	data, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"solar_reduction_kwh_day": {`) {
+		t.Fatalf("%s has no {value, units} objects:\n%s", paths[1], data)
+	}
+
+	var warn bytes.Buffer
+	outputs, err := loadResultOutputs(paths, &warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warn.Len() > 0 {
+		t.Errorf("unexpected warnings: %s", warn.String())
+	}
+	if !reflect.DeepEqual(outputs[1], outputs[0]) {
+		t.Errorf("the file with units read as\n%+v\nwant\n%+v", outputs[1], outputs[0])
+	}
+	if outputs[1].Roof == nil || outputs[1].Roof.AnnualCostSaved != output.Roof.AnnualCostSaved || len(outputs[1].Intermediates) == 0 {
+		t.Errorf("roof %+v and %d intermediates, want the roof savings and the intermediates",
+			outputs[1].Roof, len(outputs[1].Intermediates))
+	}
+}
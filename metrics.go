@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds in seconds of the calculation
+// duration histogram. A calculation takes microseconds unless it waits on
+// an irradiance, price or cost hook lookup.
+var durationBuckets = []float64{0.0001, 0.001, 0.01, 0.1, 0.5, 1, 5, 10}
+
+// serveMetrics counts the serve command's requests and calculations and
+// writes them in the Prometheus text exposition format. A nil
+// *serveMetrics records nothing, so handlers need not check --metrics.
+type serveMetrics struct {
+	mu          sync.Mutex
+	requests    map[[2]string]uint64 // {path, status code}
+	failures    map[string]uint64    // validation failures by path
+	bucketCount []uint64             // per durationBuckets entry, not cumulative
+	sum         float64
+	count       uint64
+}
+
+func newServeMetrics() *serveMetrics {
+	return &serveMetrics{
+		requests:    make(map[[2]string]uint64),
+		failures:    make(map[string]uint64),
+		bucketCount: make([]uint64, len(durationBuckets)+1),
+	}
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrument counts the requests handled by next under path.
+func (m *serveMetrics) instrument(path string, next http.HandlerFunc) http.HandlerFunc {
+	if m == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		m.mu.Lock()
+		m.requests[[2]string{path, strconv.Itoa(recorder.status)}]++
+		m.mu.Unlock()
+	}
+}
+
+// validationFailed counts a scenario under path that failed validation.
+func (m *serveMetrics) validationFailed(path string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.failures[path]++
+	m.mu.Unlock()
+}
+
+// observe records how long one calculation took.
+func (m *serveMetrics) observe(d time.Duration) {
+	if m == nil {
+		return
+	}
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(durationBuckets, seconds)
+	m.mu.Lock()
+	m.bucketCount[i]++
+	m.sum += seconds
+	m.count++
+	m.mu.Unlock()
+}
+
+// writeTo writes every metric in the Prometheus text format.
+func (m *serveMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP solar_calc_requests_total HTTP requests by path and status code.")
+	fmt.Fprintln(w, "# TYPE solar_calc_requests_total counter")
+	keys := make([][2]string, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "solar_calc_requests_total{path=%q,code=%q} %d\n", key[0], key[1], m.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP solar_calc_validation_failures_total Scenarios rejected as invalid, by path.")
+	fmt.Fprintln(w, "# TYPE solar_calc_validation_failures_total counter")
+	paths := make([]string, 0, len(m.failures))
+	for path := range m.failures {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(w, "solar_calc_validation_failures_total{path=%q} %d\n", path, m.failures[path])
+	}
+
+	fmt.Fprintln(w, "# HELP solar_calc_calculation_duration_seconds Time to calculate one scenario.")
+	fmt.Fprintln(w, "# TYPE solar_calc_calculation_duration_seconds histogram")
+	var cumulative uint64
+	for i, bound := range durationBuckets {
+		cumulative += m.bucketCount[i]
+		fmt.Fprintf(w, "solar_calc_calculation_duration_seconds_bucket{le=%q} %d\n",
+			strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "solar_calc_calculation_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.count)
+	fmt.Fprintf(w, "solar_calc_calculation_duration_seconds_sum %g\n", m.sum)
+	fmt.Fprintf(w, "solar_calc_calculation_duration_seconds_count %d\n", m.count)
+}
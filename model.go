@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// defaultModel is the name the built-in factor chain is registered as.
+const defaultModel = "default"
+
+// Model turns a validated scenario into the window result: the cooling
+// load, electricity and cost saved, the peak reduction and the
+// operating factor. Everything computeResult adds around it, such as the
+// cost hook, emissions, roof and payback, is the same for every model.
+type Model interface {
+	Compute(config Config) Result
+}
+
+// ModelFunc adapts a plain function to Model.
+type ModelFunc func(config Config) Result
+
+func (f ModelFunc) Compute(config Config) Result {
+	return f(config)
+}
+
+// models are the --model choices by name.
+var models = map[string]Model{
+	defaultModel: ModelFunc(calculateCoolingSavings),
+}
+
+// registerModel adds model as the --model called name. A custom build
+// calls it from an init function in its own file of this package, e.g.
+//
+//	func init() { registerModel("measured-shading", shadingModel{}) }
+//
+// It panics if the name is taken.
+func registerModel(name string, model Model) {
+	if _, ok := models[name]; ok {
+		panic(fmt.Sprintf("model %q registered twice", name))
+	}
+	models[name] = model
+}
+
+// modelNames lists the registered models, sorted.
+func modelNames() []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// modelOf returns config's model, the default when none is named.
+// Validate has checked the name is registered.
+func modelOf(config Config) Model {
+	if model, ok := models[config.Model]; ok {
+		return model
+	}
+	return models[defaultModel]
+}
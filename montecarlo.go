@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+)
+
+// monteCarloSpread is the relative standard deviation each calculation
+// input is sampled with, by its confidence level.
+var monteCarloSpread = map[string]float64{
+	confidenceMeasured:  0.05,
+	confidenceEstimated: 0.15,
+	confidenceDefault:   0.30,
+}
+
+// monteCarloLimits bounds the inputs that are fractions or bounded by the
+// calendar, so a sample stays physical.
+var monteCarloLimits = map[string]float64{
+	"shgc":                1,
+	"transmission_factor": 1,
+	"operating_hours":     24,
+	"operating_days":      7,
+}
+
+// monteCarloRisk is the distribution of the savings and, with a project
+// cost, of the simple payback over the samples.
+type monteCarloRisk struct {
+	Samples     int
+	Expected    float64         // net annual savings of the inputs as given, $/year
+	Savings     *quantileSketch // net annual savings, $/year
+	Payback     *quantileSketch // years, of the samples that pay back
+	Never       int             // samples saving nothing, which never pay back
+	ProjectCost float64
+}
+
+// neverProbability is the share of samples that never pay back.
+func (m monteCarloRisk) neverProbability() float64 {
+	return float64(m.Never) / float64(m.Samples)
+}
+
+// paybackQuantile is the q-quantile of the payback over every sample,
+// +Inf when it falls among those that never pay back.
+func (m monteCarloRisk) paybackQuantile(q float64) float64 {
+	paying := 1 - m.neverProbability()
+	if q >= paying {
+		return math.Inf(1)
+	}
+	return m.Payback.quantile(q / paying)
+}
+
+// monteCarloFactor draws the factor an input is scaled by: normal with
+// the given relative spread, clamped to two standard deviations. headroom
+// is the relative room under the input's limit, +Inf when it has none;
+// when it is narrower than two deviations the normal is truncated to it
+// on both sides, so a sample is as likely above the input as below it and
+// an input at its limit is held fixed.
+func monteCarloFactor(rng *rand.Rand, spread, headroom float64) float64 {
+	if spread <= 0 {
+		return 1
+	}
+	bound := headroom / spread
+	if bound >= 2 {
+		return 1 + spread*max(-2, min(2, rng.NormFloat64()))
+	}
+	if bound <= 0 {
+		return 1
+	}
+	// Invert the normal CDF over [-bound, bound].
+	z := math.Sqrt2 * math.Erfinv(math.Erf(bound/math.Sqrt2)*(2*rng.Float64()-1))
+	return 1 + spread*z
+}
+
+// monteCarlo recomputes config n times, each confidence input scaled by
+// a monteCarloFactor whose spread follows its confidence level. The
+// retrofit cost is held fixed. The seed is fixed so a run always reports
+// the same distribution.
+func monteCarlo(config Config, n int, confidence map[string]string, solar func() (SolarResource, error)) (monteCarloRisk, error) {
+	if config.CostHook != "" {
+		return monteCarloRisk{}, errors.New("--monte-carlo would run the cost hook for every sample; drop --cost-hook")
+	}
+	risk := monteCarloRisk{Samples: n, Savings: newQuantileSketch(), Payback: newQuantileSketch(),
+		ProjectCost: config.ProjectCost}
+	expected, err := computeResult(config, solar)
+	if err != nil {
+		return risk, err
+	}
+	risk.Expected = expected.NetAnnualSavings
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < n; i++ {
+		scenario := config
+		for _, key := range confidenceInputs {
+			field, err := configField(&scenario, key)
+			if err != nil {
+				return risk, err
+			}
+			value := field.Float()
+			headroom := math.Inf(1)
+			if limit, ok := monteCarloLimits[key]; ok && value > 0 {
+				headroom = limit/value - 1
+			}
+			field.SetFloat(value * monteCarloFactor(rng, monteCarloSpread[confidence[key]], headroom))
+		}
+		result, err := computeResult(scenario, solar)
+		if err != nil {
+			return risk, err
+		}
+		risk.Savings.add(result.NetAnnualSavings)
+		if config.ProjectCost > 0 {
+			if result.NetAnnualSavings > 0 {
+				risk.Payback.add(config.ProjectCost / result.NetAnnualSavings)
+			} else {
+				risk.Never++
+			}
+		}
+	}
+	return risk, nil
+}
+
+// printMonteCarlo reports the savings percentiles and, with a project
+// cost, the median and p90 payback and the chance of never paying back.
+func printMonteCarlo(w io.Writer, risk monteCarloRisk) {
+	fmt.Fprintf(w, "Monte Carlo over %d samples, inputs varied by %.0f%% (measured), %.0f%% (estimated)\n",
+		risk.Samples, 100*monteCarloSpread[confidenceMeasured], 100*monteCarloSpread[confidenceEstimated])
+	fmt.Fprintf(w, "and %.0f%% (default) standard deviation:\n", 100*monteCarloSpread[confidenceDefault])
+	fmt.Fprintf(w, "Net annual savings: p10 %.2f, p50 %.2f, p90 %.2f $/year (mean %.2f, inputs as given %.2f)\n",
+		risk.Savings.quantile(0.1), risk.Savings.quantile(0.5), risk.Savings.quantile(0.9),
+		risk.Savings.mean(), risk.Expected)
+	if risk.ProjectCost <= 0 {
+		fmt.Fprintln(w, "Give --project-cost for the payback distribution")
+		return
+	}
+	years := func(q float64) string {
+		if v := risk.paybackQuantile(q); !math.IsInf(v, 1) {
+			return fmt.Sprintf("%.1f years", v)
+		}
+		return "never"
+	}
+	fmt.Fprintf(w, "Simple payback on $%.2f: p50 %s, p90 %s\n", risk.ProjectCost, years(0.5), years(0.9))
+	fmt.Fprintf(w, "Probability of never paying back: %.1f%%\n", 100*risk.neverProbability())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"testing"
+)
+
+func TestMonteCarloFactorIsSymmetricInsideTheLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		spread   float64
+		headroom float64
+	}{
+		{"unbounded", 0.30, math.Inf(1)},
+		{"room for two deviations", 0.30, 0.60},
+		{"narrow room", 0.30, 0.10},
+		{"at the limit", 0.30, 0},
+		{"no spread", 0, math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			factors := make([]float64, 20000)
+			sum := 0.0
+			for i := range factors {
+				factors[i] = monteCarloFactor(rng, tt.spread, tt.headroom)
+				sum += factors[i]
+			}
+			sort.Float64s(factors)
+			width := min(2*tt.spread, tt.headroom)
+			if lo, hi := factors[0], factors[len(factors)-1]; lo < 1-width-1e-12 || hi > 1+width+1e-12 {
+				t.Errorf("factors span [%g, %g], want within 1±%g", lo, hi, width)
+			}
+			tolerance := 0.01*width + 1e-12
+			if median := factors[len(factors)/2]; math.Abs(median-1) > tolerance {
+				t.Errorf("median factor = %g, want 1", median)
+			}
+			if mean := sum / float64(len(factors)); math.Abs(mean-1) > tolerance {
+				t.Errorf("mean factor = %g, want 1", mean)
+			}
+		})
+	}
+}
+
+func TestMonteCarloMedianStaysNearTheInputs(t *testing.T) {
+	config := DefaultConfig()
+	config.SolarReduction, config.ElectricityCost = 100, 0.15
+	confidence := make(map[string]string, len(confidenceInputs))
+	for _, key := range confidenceInputs {
+		confidence[key] = confidenceDefault
+	}
+	risk, err := monteCarlo(config, 2000, confidence, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := computeResult(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if risk.Expected != result.NetAnnualSavings {
+		t.Errorf("Expected = %g, want %g", risk.Expected, result.NetAnnualSavings)
+	}
+	// Hours and days default to their limits, so they are held there; the
+	// other inputs spread either way, leaving the median near the inputs.
+	if p50 := risk.Savings.quantile(0.5); p50 < 0.8*risk.Expected {
+		t.Errorf("p50 = %.2f, far below %.2f", p50, risk.Expected)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// appendNDJSON appends each output to path as one compact JSON object per
+// line. All lines are written with a single write to a file opened with
+// O_APPEND, so concurrent runs appending to the same log cannot interleave
+// partial lines.
+func appendNDJSON(path string, outputs ...ResultOutput) error {
+	var data []byte
+	for _, output := range outputs {
+		line, err := canonicalJSON(output, "")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %v", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open NDJSON log: %v", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write NDJSON log: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write NDJSON log: %v", err)
+	}
+	return nil
+}
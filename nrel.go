@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// pvwattsURL is the PVWatts endpoint; tests point it at a local server.
+var pvwattsURL = "https://developer.nrel.gov/api/pvwatts/v8.json"
+
+// SolarResource is the irradiance used to estimate the solar reduction.
+type SolarResource struct {
+	AnnualGHI  float64     `json:"annual_ghi"`  // kWh/m²/day
+	MonthlyGHI [12]float64 `json:"monthly_ghi"` // kWh/m²/day
+	Source     string      `json:"source"`
+
+	// CacheAge is how old the disk cache entry served was; zero for
+	// live and bundled data.
+	CacheAge time.Duration `json:"-"`
+}
+
+type pvwattsResponse struct {
+	Errors  []string `json:"errors"`
+	Outputs struct {
+		SolradAnnual  float64   `json:"solrad_annual"`
+		SolradMonthly []float64 `json:"solrad_monthly"`
+	} `json:"outputs"`
+}
+
+// fetchSolarResource returns the irradiance for lat/lng, reading the disk
+// cache first and querying PVWatts on a miss or an entry older than ttl.
+// When that query fails an expired entry is still returned, with a
+// warning saying so.
+func fetchSolarResource(apiKey string, lat, lng float64, ttl time.Duration) (SolarResource, string, error) {
+	// Coordinates are rounded so that nearby lookups share an entry.
+	cacheName := fmt.Sprintf("nrel_%.2f_%.2f.json", lat, lng)
+	var cached SolarResource
+	age, found := readCache(cacheName, &cached)
+	if found {
+		cached.CacheAge = age
+		if cacheFresh(age, ttl) {
+			return cached, "", nil
+		}
+	}
+
+	resource, err := queryPVWatts(apiKey, lat, lng)
+	if err != nil {
+		if found {
+			return cached, fmt.Sprintf("%v; using the cached irradiance, %s and past --cache-ttl", err, cacheAge(age)), nil
+		}
+		return SolarResource{}, "", err
+	}
+	writeCache(cacheName, resource)
+	return resource, "", nil
+}
+
+// queryPVWatts asks the NREL PVWatts API for the irradiance at lat/lng.
+func queryPVWatts(apiKey string, lat, lng float64) (SolarResource, error) {
+	params := url.Values{}
+	params.Set("api_key", apiKey)
+	params.Set("lat", strconv.FormatFloat(lat, 'f', 4, 64))
+	params.Set("lon", strconv.FormatFloat(lng, 'f', 4, 64))
+	params.Set("system_capacity", "1")
+	params.Set("module_type", "0")
+	params.Set("losses", "14")
+	params.Set("array_type", "0")
+	params.Set("tilt", "0")
+	params.Set("azimuth", "180")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(pvwattsURL + "?" + params.Encode())
+	if err != nil {
+		// The request URL carries the API key, so report only the cause.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return SolarResource{}, fmt.Errorf("failed to query NREL: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return SolarResource{}, fmt.Errorf("failed to read NREL response: %v", err)
+	}
+
+	var parsed pvwattsResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return SolarResource{}, fmt.Errorf("failed to parse NREL response: %v", err)
+	}
+	if len(parsed.Errors) > 0 {
+		return SolarResource{}, fmt.Errorf("NREL returned an error: %s", parsed.Errors[0])
+	}
+	if resp.StatusCode != http.StatusOK {
+		return SolarResource{}, fmt.Errorf("NREL returned status %s", resp.Status)
+	}
+	if len(parsed.Outputs.SolradMonthly) != 12 {
+		return SolarResource{}, fmt.Errorf("NREL returned %d monthly values, expected 12",
+			len(parsed.Outputs.SolradMonthly))
+	}
+
+	resource := SolarResource{
+		AnnualGHI: parsed.Outputs.SolradAnnual,
+		Source:    "NREL PVWatts",
+	}
+	copy(resource.MonthlyGHI[:], parsed.Outputs.SolradMonthly)
+	return resource, nil
+}
+
+// resolveSolarResource picks the irradiance for the configured site,
+// preferring live NREL data and falling back to the bundled table. The
+// returned warning is non-empty when a live lookup was attempted and
+// failed.
+func resolveSolarResource(config Config) (SolarResource, string, error) {
+	var warning string
+
+	if config.NRELAPIKey != "" && (config.Latitude != 0 || config.Longitude != 0) {
+		ttl, err := config.cacheTTL()
+		if err != nil {
+			return SolarResource{}, "", err
+		}
+		resource, cacheWarning, err := fetchSolarResource(config.NRELAPIKey, config.Latitude, config.Longitude, ttl)
+		if err == nil {
+			return resource, cacheWarning, nil
+		}
+		warning = fmt.Sprintf("%v; using bundled irradiance for %s", err, config.Location)
+	}
+
+	data, ok := lookupLocation(config.Location)
+	if !ok {
+		return SolarResource{}, warning, fmt.Errorf("no bundled irradiance for location %q", config.Location)
+	}
+
+	resource := SolarResource{AnnualGHI: data.GHI, Source: "bundled"}
+	for i := range resource.MonthlyGHI {
+		resource.MonthlyGHI[i] = data.GHI
+	}
+	return resource, warning, nil
+}
+
+// newSolarLookup returns a function resolving the irradiance for config at
+// most once, since both the reduction estimate and the roof component may
+// need it. Fallback warnings are written to warn and a note that the
+// irradiance came from the cache to out.
+func newSolarLookup(config *Config, warn, out io.Writer) func() (SolarResource, error) {
+	var solar *SolarResource
+	return func() (SolarResource, error) {
+		if solar == nil {
+			resource, warning, err := resolveSolarResource(*config)
+			if warning != "" {
+				fmt.Fprintf(warn, "Warning: %s\n", warning)
+			}
+			if err != nil {
+				return SolarResource{}, err
+			}
+			if resource.CacheAge > 0 && warning == "" {
+				fmt.Fprintf(out, "Note: %s irradiance read from cache, %s (--cache-ttl %s)\n",
+					resource.Source, cacheAge(resource.CacheAge), config.CacheTTL)
+			}
+			solar = &resource
+		}
+		return *solar, nil
+	}
+}
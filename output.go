@@ -0,0 +1,691 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// outputSchemaVersion is bumped whenever a ResultOutput field is renamed,
+// removed or changes meaning, so tools combining result files can tell
+// incompatible runs apart. Adding fields does not require a bump.
+// Version 2 renamed daily_cost_saved_usd, which held the annual figure, to
+// annual_cost_saved_usd.
+const outputSchemaVersion = 2
+
+type ResultOutput struct {
+	// metadata
+	SchemaVersion    int    `json:"schema_version"`
+	Timestamp        string `json:"timestamp"`
+	ScenarioName     string `json:"name,omitempty"`
+	Location         string `json:"location"`
+	BuildingType     string `json:"building_type"`
+	InputHash        string `json:"input_hash"`
+	ReproduceCommand string `json:"reproduce_command"`
+	Mode             string `json:"mode"` // cooling or heating
+
+	// inputs
+	SolarReduction        float64 `json:"solar_reduction_kwh_day"`
+	ElectricityCost       float64 `json:"electricity_cost_per_kwh"`
+	ElectricityCostSource string  `json:"electricity_cost_source"`
+	AC_COP                float64 `json:"ac_cop"`
+	EffectiveCOP          float64 `json:"effective_cop,omitempty"`
+	SHGC                  float64 `json:"shgc"`
+	WWR                   float64 `json:"wwr"`
+	TransmissionFactor    float64 `json:"transmission_factor"`
+	TimeLagFactor         float64 `json:"time_lag_factor"`
+	MedicalEquipFactor    float64 `json:"medical_equip_factor"`
+	OperatingHours        float64 `json:"operating_hours_per_day"`
+	OperatingDays         float64 `json:"operating_days_per_week"`
+	PVOffsetFraction      float64 `json:"pv_offset_fraction,omitempty"`
+	SHGCExisting          float64 `json:"shgc_existing,omitempty"`
+	SHGCProposed          float64 `json:"shgc_proposed,omitempty"`
+
+	// results
+	CoolingLoadReduced      float64  `json:"cooling_load_reduced_kwh_day"`
+	SolarLoadReduced        float64  `json:"solar_load_reduced_kwh_day,omitempty"`
+	ConductionLoadReduced   float64  `json:"conduction_load_reduced_kwh_day,omitempty"`
+	ElectricitySaved        float64  `json:"electricity_saved_kwh_day"`
+	CompressorSaved         float64  `json:"compressor_electricity_saved_kwh_day,omitempty"`
+	GridElectricitySaved    float64  `json:"grid_electricity_saved_kwh_day,omitempty"`
+	SelfConsumptionSaved    float64  `json:"self_consumption_saved_kwh_day,omitempty"`
+	OperatingFactor         float64  `json:"operating_factor"`
+	OperatingDaysPerYear    int      `json:"operating_days_per_year,omitempty"`
+	ThermalMass             string   `json:"thermal_mass,omitempty"`
+	TimeLagHours            float64  `json:"time_lag_hours,omitempty"`
+	PeakCoolingReduced      float64  `json:"peak_cooling_reduced_kw"`
+	PeakElectricityReduced  float64  `json:"peak_electricity_reduced_kw"`
+	PeakTonsReduced         float64  `json:"peak_tons_reduced"`
+	AnnualCostSaved         float64  `json:"annual_cost_saved_usd"`
+	ShapedRate              float64  `json:"shaped_rate_usd_per_kwh,omitempty"`
+	CoincidentDemand        float64  `json:"coincident_demand_reduced_kw,omitempty"`
+	DRRevenue               float64  `json:"dr_incentive_usd_year,omitempty"`
+	HeatingPenalty          float64  `json:"heating_penalty_usd_year,omitempty"`
+	NetAnnualSavings        float64  `json:"net_annual_savings_usd"`
+	PercentOfBill           float64  `json:"percent_of_bill,omitempty"`
+	PaybackYears            *float64 `json:"payback_years,omitempty"` // absent when it never pays back
+	EmissionsBasis          string   `json:"emissions_basis"`
+	GridCO2                 float64  `json:"grid_co2_kg_per_kwh"`
+	GridCO2Source           string   `json:"grid_co2_source"`
+	CO2Avoided              float64  `json:"co2_avoided_kg_day"`
+	AnnualCO2Avoided        float64  `json:"co2_avoided_kg_year"`
+	PercentEmissionsReduced float64  `json:"percent_emissions_reduced,omitempty"`
+	CarbonValue             float64  `json:"carbon_value_usd_year,omitempty"`
+	TotalAnnualBenefit      float64  `json:"total_annual_benefit_usd,omitempty"`
+	DaysDelayed             int      `json:"days_delayed,omitempty"`
+	ForegoneSavings         float64  `json:"foregone_savings_usd,omitempty"`
+
+	Roof         *RoofResult         `json:"roof,omitempty"`
+	Sizing       *SizingResult       `json:"equipment_sizing,omitempty"`
+	Intensity    *SavingsIntensity   `json:"savings_intensity,omitempty"`
+	CodeBaseline *CodeBaselineResult `json:"code_baseline,omitempty"`
+	Baseline     *BaselineDelta      `json:"baseline,omitempty"`
+	WindowGroups []WindowGroupResult `json:"window_groups,omitempty"`
+	Monthly      []MonthSavings      `json:"monthly_savings,omitempty"`
+	Confidence   map[string]string   `json:"confidence,omitempty"`
+
+	Intermediates map[string]Quantity `json:"intermediates,omitempty"`
+
+	// Units label the quantities for --json-with-units.
+	Units Units `json:"-"`
+}
+
+// newResultOutput builds the output record for a run made at
+// outputTime(config).
+func newResultOutput(result Result, config Config) ResultOutput {
+	return resultOutputAt(result, config, outputTime(config))
+}
+
+// outputTime is the time stamped on results and output file names:
+// --output-timestamp when set, for byte-stable output, or else now.
+// Validate has checked the override parses.
+func outputTime(config Config) time.Time {
+	if config.OutputTimestamp != "" {
+		if t, err := time.Parse(time.RFC3339, config.OutputTimestamp); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// fileTimestamp formats t for output file names.
+func fileTimestamp(t time.Time) string {
+	return t.Format("2006-01-02_150405")
+}
+
+// resultOutputAt builds the output record stamped with at. It depends on
+// nothing else, so a fixed Result and time always render identically.
+func resultOutputAt(result Result, config Config, at time.Time) ResultOutput {
+	output := ResultOutput{
+		SchemaVersion:           outputSchemaVersion,
+		Timestamp:               at.Format(time.RFC3339),
+		ScenarioName:            config.ScenarioName,
+		Location:                result.Assumptions.Location,
+		BuildingType:            result.Assumptions.BuildingType,
+		InputHash:               inputHash(config),
+		ReproduceCommand:        reproduceCommand(config),
+		Mode:                    result.Mode,
+		SolarReduction:          result.TotalSolarReduction,
+		ElectricityCost:         result.Assumptions.ElectricityCost,
+		ElectricityCostSource:   result.Assumptions.ElectricityCostSource,
+		AC_COP:                  result.Assumptions.AC_COP,
+		EffectiveCOP:            result.Assumptions.EffectiveCOP,
+		SHGC:                    result.Assumptions.SHGC,
+		WWR:                     result.Assumptions.WWR,
+		TransmissionFactor:      result.Assumptions.TransmissionFactor,
+		TimeLagFactor:           result.Assumptions.TimeLagFactor,
+		MedicalEquipFactor:      result.Assumptions.MedicalEquipFactor,
+		OperatingHours:          result.Assumptions.OperatingHours,
+		OperatingDays:           result.Assumptions.OperatingDays,
+		CoolingLoadReduced:      result.CoolingLoadReduced,
+		ElectricitySaved:        result.ElectricitySaved,
+		CompressorSaved:         result.CompressorSaved,
+		OperatingFactor:         result.OperatingFactor,
+		OperatingDaysPerYear:    result.OperatingDaysPerYear,
+		ThermalMass:             config.ThermalMass,
+		TimeLagHours:            result.TimeLagHours,
+		PeakCoolingReduced:      result.PeakCoolingReduced,
+		PeakElectricityReduced:  result.PeakElectricityReduced,
+		PeakTonsReduced:         result.PeakTonsReduced,
+		AnnualCostSaved:         result.AnnualCostSaved,
+		ShapedRate:              result.ShapedRate,
+		CoincidentDemand:        result.CoincidentDemand,
+		DRRevenue:               result.DRRevenue,
+		HeatingPenalty:          result.HeatingPenalty,
+		NetAnnualSavings:        result.NetAnnualSavings,
+		PercentOfBill:           result.PercentOfBill,
+		EmissionsBasis:          result.EmissionsBasis,
+		GridCO2:                 result.GridCO2,
+		GridCO2Source:           result.GridCO2Source,
+		CO2Avoided:              result.CO2Avoided,
+		AnnualCO2Avoided:        result.AnnualCO2Avoided,
+		PercentEmissionsReduced: result.PercentEmissionsReduced,
+		CarbonValue:             result.CarbonValue,
+		TotalAnnualBenefit:      result.TotalAnnualBenefit,
+		DaysDelayed:             result.DaysDelayed,
+		ForegoneSavings:         result.ForegoneSavings,
+		Roof:                    result.Roof,
+		Sizing:                  result.Sizing,
+		Intensity:               result.Intensity,
+		CodeBaseline:            result.CodeBaseline,
+		Baseline:                result.Baseline,
+		WindowGroups:            result.WindowGroups,
+		Monthly:                 result.Monthly,
+		Confidence:              result.Confidence,
+		Units:                   result.Assumptions.Units,
+	}
+	// The grid/self-consumption split only differs from the total when
+	// PV offsetting is enabled.
+	if config.PVOffsetFraction > 0 {
+		output.PVOffsetFraction = config.PVOffsetFraction
+		output.GridElectricitySaved = result.GridElectricitySaved
+		output.SelfConsumptionSaved = result.SelfConsumptionSaved
+	}
+	// Likewise the solar/conduction split only with --u-factor.
+	if result.ConductionLoadReduced > 0 {
+		output.SolarLoadReduced = result.SolarLoadReduced
+		output.ConductionLoadReduced = result.ConductionLoadReduced
+	}
+	if result.PaybackYears > 0 && !math.IsInf(result.PaybackYears, 1) {
+		payback := result.PaybackYears
+		output.PaybackYears = &payback
+	}
+	if config.SHGCExisting > 0 {
+		output.SHGCExisting = config.SHGCExisting
+		output.SHGCProposed = config.SHGCProposed
+	}
+	if config.DumpIntermediates {
+		output.Intermediates = intermediates(result, config)
+	}
+	return output
+}
+
+// csvColumnNames are the canonical names --columns selects csvHeaders
+// by, in the same order.
+var csvColumnNames = []string{
+	"timestamp", "name", "location", "building_type", "input_hash",
+	"solar_reduction", "electricity_cost", "electricity_cost_source",
+	"ac_cop", "shgc", "wwr",
+	"transmission_factor", "time_lag_factor", "medical_equip_factor",
+	"operating_hours", "operating_days",
+	"cooling_load_reduced", "electricity_saved",
+	"operating_factor", "peak_cooling_reduced",
+	"peak_electricity_reduced", "peak_tons_reduced",
+	"annual_cost_saved",
+	"roof_cooling_load_reduced", "roof_annual_cost_saved",
+	"co2_avoided",
+}
+
+var csvHeaders = []string{
+	"Timestamp", "Scenario Name", "Location", "Building Type", "Input Hash",
+	"Solar Reduction (kWh/day)", "Electricity Cost ($/kWh)", "Electricity Cost Source",
+	"AC COP", "SHGC", "WWR",
+	"Transmission Factor", "Time Lag Factor", "Medical Equipment Factor",
+	"Operating Hours (h/day)", "Operating Days (days/week)",
+	"Cooling Load Reduced (kWh/day)", "Electricity Saved (kWh/day)",
+	"Operating Factor", "Peak Cooling Reduced (kW thermal)",
+	"Peak Electricity Reduced (kW electric)", "Peak Tons Reduced",
+	"Annual Cost Saved ($)",
+	"Roof Cooling Load Reduced (kWh/day)", "Roof Annual Cost Saved ($)",
+	"CO2 Avoided (kg/year)",
+}
+
+// csvRow formats output as a CSV record. The inputs keep fixed decimals;
+// the results are rounded to sigFigs significant figures when it is set.
+func csvRow(output ResultOutput, sigFigs int) []string {
+	result := func(v float64, decimals int) string {
+		return formatFigures(v, decimals, sigFigs)
+	}
+	roofLoad, roofSaved := "", ""
+	if output.Roof != nil {
+		roofLoad = result(output.Roof.CoolingLoadReduced, 2)
+		roofSaved = result(output.Roof.AnnualCostSaved, 2)
+	}
+
+	return []string{
+		output.Timestamp, output.ScenarioName, output.Location, output.BuildingType, output.InputHash,
+		fmt.Sprintf("%.2f", output.SolarReduction),
+		fmt.Sprintf("%.3f", output.ElectricityCost),
+		output.ElectricityCostSource,
+		fmt.Sprintf("%.1f", output.AC_COP),
+		fmt.Sprintf("%.2f", output.SHGC),
+		fmt.Sprintf("%.2f", output.WWR),
+		fmt.Sprintf("%.2f", output.TransmissionFactor),
+		fmt.Sprintf("%.2f", output.TimeLagFactor),
+		fmt.Sprintf("%.2f", output.MedicalEquipFactor),
+		fmt.Sprintf("%.1f", output.OperatingHours),
+		fmt.Sprintf("%.1f", output.OperatingDays),
+		result(output.CoolingLoadReduced, 2),
+		result(output.ElectricitySaved, 2),
+		fmt.Sprintf("%.3f", output.OperatingFactor),
+		result(output.PeakCoolingReduced, 2),
+		result(output.PeakElectricityReduced, 2),
+		result(output.PeakTonsReduced, 3),
+		result(output.AnnualCostSaved, 2),
+		roofLoad, roofSaved,
+		result(output.AnnualCO2Avoided, 1),
+	}
+}
+
+// outputFormat controls how result files are encoded.
+type outputFormat struct {
+	Gzip  bool
+	Comma rune // CSV field delimiter
+	CRLF  bool // CSV line ending
+
+	// Columns are the indexes into csvHeaders to write, or nil for all.
+	Columns []int
+
+	SigFigs int // significant figures of the results, 0 for fixed decimals
+
+	CompactJSON bool // minified JSON files instead of indented
+	WithUnits   bool // results as {value, units} objects instead of bare numbers
+
+	// Now is the clock that stamps output file names.
+	Now func() time.Time
+}
+
+// selectColumns picks the configured columns out of a full CSV record.
+func (f outputFormat) selectColumns(record []string) []string {
+	if f.Columns == nil {
+		return record
+	}
+	selected := make([]string, len(f.Columns))
+	for i, column := range f.Columns {
+		selected[i] = record[column]
+	}
+	return selected
+}
+
+// parseColumns resolves a comma-separated list of canonical column names
+// to their indexes, reporting every unknown or repeated name.
+func parseColumns(list string) ([]int, error) {
+	index := make(map[string]int, len(csvColumnNames))
+	for i, name := range csvColumnNames {
+		index[name] = i
+	}
+	var columns []int
+	var errs []error
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		i, ok := index[name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("Unknown CSV column %q", name))
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("CSV column %q listed twice", name))
+		default:
+			columns = append(columns, i)
+		}
+		seen[name] = true
+	}
+	return columns, errors.Join(errs...)
+}
+
+// outputFormatOf returns the output encoding set in config. The CSV
+// delimiter must be a single rune that encoding/csv can write.
+func outputFormatOf(config Config) (outputFormat, error) {
+	format := outputFormat{Gzip: config.Gzip, Comma: ',', CRLF: config.CSVCRLF,
+		SigFigs: config.SigFigs, CompactJSON: config.JSONCompact, WithUnits: config.JSONWithUnits,
+		Now: func() time.Time { return outputTime(config) }}
+	if config.CSVColumns != "" {
+		columns, err := parseColumns(config.CSVColumns)
+		if err != nil {
+			return format, err
+		}
+		format.Columns = columns
+	}
+	if config.CSVDelimiter == "" {
+		return format, nil
+	}
+	r, size := utf8.DecodeRuneInString(config.CSVDelimiter)
+	if size != len(config.CSVDelimiter) || r == utf8.RuneError ||
+		r == '"' || r == '\r' || r == '\n' || r == '\uFEFF' {
+		return format, fmt.Errorf("CSV delimiter must be a single character other than a quote or newline, not %q",
+			config.CSVDelimiter)
+	}
+	format.Comma = r
+	return format, nil
+}
+
+// newCSVWriter returns a csv.Writer for w using format's delimiter and
+// line ending.
+func newCSVWriter(w io.Writer, format outputFormat) *csv.Writer {
+	writer := csv.NewWriter(w)
+	writer.Comma = format.Comma
+	writer.UseCRLF = format.CRLF
+	return writer
+}
+
+// compressedFile closes its gzip stream before the underlying file.
+type compressedFile struct {
+	*gzip.Writer
+	file *os.File
+}
+
+func (c compressedFile) Close() error {
+	if err := c.Writer.Close(); err != nil {
+		c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
+
+// outputFile is a result file written under a temporary name in its
+// final directory, so that a failed run never leaves a partial file at
+// the real path.
+type outputFile struct {
+	io.WriteCloser
+	tmp  string // name written to
+	path string // name it is renamed to on commit
+}
+
+// createOutputFile starts writing path, or path.gz holding a gzip stream
+// when compress is set. Nothing appears at path until commitOutputFiles.
+func createOutputFile(path string, compress bool) (*outputFile, error) {
+	if compress {
+		path += ".gz"
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	out := &outputFile{WriteCloser: file, tmp: file.Name(), path: path}
+	if compress {
+		out.WriteCloser = compressedFile{gzip.NewWriter(file), file}
+	}
+	return out, nil
+}
+
+// discard closes f and removes its temporary file.
+func (f *outputFile) discard() {
+	f.Close()
+	os.Remove(f.tmp)
+}
+
+// commitOutputFiles renames closed output files into place. If any rename
+// fails, those already renamed and the remaining temporaries are removed,
+// so either every file appears or none do.
+func commitOutputFiles(files ...*outputFile) error {
+	for i, f := range files {
+		if err := os.Rename(f.tmp, f.path); err != nil {
+			for _, done := range files[:i] {
+				os.Remove(done.path)
+			}
+			for _, pending := range files[i:] {
+				os.Remove(pending.tmp)
+			}
+			return fmt.Errorf("failed to save %s: %v", f.path, err)
+		}
+	}
+	return nil
+}
+
+// checkOutputDir creates dir if needed and writes and removes a
+// temporary file in it, so that an unwritable output directory is
+// reported before a calculation rather than after. Errors are ErrWrite.
+func checkOutputDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return markError(ErrWrite, fmt.Errorf("cannot create output directory: %v", err))
+	}
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return markError(ErrWrite, fmt.Errorf("output directory %s is not writable (permission denied); "+
+				"choose another with --output", dir))
+		}
+		return markError(ErrWrite, fmt.Errorf("output directory %s is not writable: %v", dir, err))
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// writeOutputFiles writes jsonValue as JSON and outputs as CSV
+// rows to name.json and name.csv in dir, encoded as format specifies, and
+// returns the paths written. Both files are written to temporaries first
+// and only renamed into place once both succeed. Errors are ErrWrite.
+func writeOutputFiles(dir, name string, format outputFormat, jsonValue any, outputs []ResultOutput) (jsonPath, csvPath string, err error) {
+	defer func() { err = markError(ErrWrite, err) }()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	jsonFile, err := createOutputFile(filepath.Join(dir, name+".json"), format.Gzip)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create JSON file: %v", err)
+	}
+	if format.WithUnits {
+		if jsonValue, err = resultsWithUnits(jsonValue); err != nil {
+			jsonFile.discard()
+			return "", "", fmt.Errorf("failed to marshal JSON: %v", err)
+		}
+	}
+	if err := renderJSON(jsonFile, jsonValue, format.CompactJSON); err != nil {
+		jsonFile.discard()
+		return "", "", err
+	}
+	if err := jsonFile.Close(); err != nil {
+		os.Remove(jsonFile.tmp)
+		return "", "", fmt.Errorf("failed to write JSON file: %v", err)
+	}
+
+	csvFile, err := createOutputFile(filepath.Join(dir, name+".csv"), format.Gzip)
+	if err != nil {
+		os.Remove(jsonFile.tmp)
+		return "", "", fmt.Errorf("failed to create CSV file: %v", err)
+	}
+	if err := writeCSV(csvFile, outputs, format); err != nil {
+		csvFile.discard()
+		os.Remove(jsonFile.tmp)
+		return "", "", err
+	}
+	if err := csvFile.Close(); err != nil {
+		os.Remove(csvFile.tmp)
+		os.Remove(jsonFile.tmp)
+		return "", "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+
+	if err := commitOutputFiles(jsonFile, csvFile); err != nil {
+		return "", "", err
+	}
+	return jsonFile.path, csvFile.path, nil
+}
+
+// resultStream writes outputs one at a time to the JSON array and CSV
+// files writeOutputFiles would write for all of them, so a batch never
+// holds its results in memory. As with writeOutputFiles, the files
+// appear only on commit.
+type resultStream struct {
+	format  outputFormat
+	json    *outputFile
+	csvFile *outputFile
+	csv     *csv.Writer
+	count   int
+}
+
+// newResultStream starts the files name.json and name.csv in dir.
+func newResultStream(dir, name string, format outputFormat) (*resultStream, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, markError(ErrWrite, fmt.Errorf("failed to create output directory: %v", err))
+	}
+	jsonFile, err := createOutputFile(filepath.Join(dir, name+".json"), format.Gzip)
+	if err != nil {
+		return nil, markError(ErrWrite, fmt.Errorf("failed to create JSON file: %v", err))
+	}
+	csvFile, err := createOutputFile(filepath.Join(dir, name+".csv"), format.Gzip)
+	if err != nil {
+		jsonFile.discard()
+		return nil, markError(ErrWrite, fmt.Errorf("failed to create CSV file: %v", err))
+	}
+	s := &resultStream{format: format, json: jsonFile, csvFile: csvFile, csv: newCSVWriter(csvFile, format)}
+	if err := s.csv.Write(format.selectColumns(csvHeaders)); err != nil {
+		s.discard()
+		return nil, markError(ErrWrite, fmt.Errorf("failed to write CSV headers: %v", err))
+	}
+	if _, err := io.WriteString(jsonFile, "["); err != nil {
+		s.discard()
+		return nil, markError(ErrWrite, fmt.Errorf("failed to write JSON file: %v", err))
+	}
+	return s, nil
+}
+
+// add writes output as the next array element and CSV row.
+func (s *resultStream) add(output ResultOutput) error {
+	var value any = output
+	if s.format.WithUnits {
+		var err error
+		if value, err = withUnits(output); err != nil {
+			return fmt.Errorf("failed to marshal JSON: %v", err)
+		}
+	}
+	// Elements are laid out as renderJSON lays out the whole array.
+	var data []byte
+	var err error
+	separator := ","
+	if s.format.CompactJSON {
+		data, err = json.Marshal(value)
+	} else {
+		data, err = json.MarshalIndent(value, "  ", "  ")
+		separator += "\n  "
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	if s.count == 0 {
+		separator = strings.TrimPrefix(separator, ",")
+	}
+	if _, err := io.WriteString(s.json, separator); err != nil {
+		return markError(ErrWrite, fmt.Errorf("failed to write JSON file: %v", err))
+	}
+	if _, err := s.json.Write(data); err != nil {
+		return markError(ErrWrite, fmt.Errorf("failed to write JSON file: %v", err))
+	}
+	if err := s.csv.Write(s.format.selectColumns(csvRow(output, s.format.SigFigs))); err != nil {
+		return markError(ErrWrite, fmt.Errorf("failed to write CSV data: %v", err))
+	}
+	s.count++
+	return nil
+}
+
+// commit ends both files and renames them into place.
+func (s *resultStream) commit() (jsonPath, csvPath string, err error) {
+	defer func() { err = markError(ErrWrite, err) }()
+	end := "]\n"
+	if !s.format.CompactJSON && s.count > 0 {
+		end = "\n]\n"
+	}
+	if _, err := io.WriteString(s.json, end); err != nil {
+		s.discard()
+		return "", "", fmt.Errorf("failed to write JSON file: %v", err)
+	}
+	s.csv.Flush()
+	if err := s.csv.Error(); err != nil {
+		s.discard()
+		return "", "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := s.json.Close(); err != nil {
+		s.discard()
+		return "", "", fmt.Errorf("failed to write JSON file: %v", err)
+	}
+	if err := s.csvFile.Close(); err != nil {
+		s.discard()
+		return "", "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := commitOutputFiles(s.json, s.csvFile); err != nil {
+		return "", "", err
+	}
+	return s.json.path, s.csvFile.path, nil
+}
+
+// discard abandons both files.
+func (s *resultStream) discard() {
+	s.json.discard()
+	s.csvFile.discard()
+}
+
+// canonicalJSON is the one encoding used for every JSON file and log
+// line, so committed results diff cleanly: struct fields in declaration
+// order, map keys sorted (encoding/json guarantees this at every depth)
+// and, when indenting, a final newline.
+func canonicalJSON(v any, indent string) ([]byte, error) {
+	if indent == "" {
+		return json.Marshal(v)
+	}
+	data, err := json.MarshalIndent(v, "", indent)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// renderJSON writes v to w as the JSON used for result files: indented,
+// or minified on one line when compact.
+func renderJSON(w io.Writer, v any, compact bool) error {
+	indent := "  "
+	if compact {
+		indent = ""
+	}
+	data, err := canonicalJSON(v, indent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	if compact {
+		data = append(data, '\n')
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write JSON file: %v", err)
+	}
+	return nil
+}
+
+// writeCSV writes the header and one row per output to w. The writer is
+// flushed explicitly so that a short write, such as a full disk, is
+// reported instead of silently truncating the file.
+func writeCSV(w io.Writer, outputs []ResultOutput, format outputFormat) error {
+	writer := newCSVWriter(w, format)
+
+	if err := writer.Write(format.selectColumns(csvHeaders)); err != nil {
+		return fmt.Errorf("failed to write CSV headers: %v", err)
+	}
+	for _, output := range outputs {
+		if err := writer.Write(format.selectColumns(csvRow(output, format.SigFigs))); err != nil {
+			return fmt.Errorf("failed to write CSV data: %v", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	return nil
+}
+
+// saveResults writes the JSON and CSV files for one run, returning the
+// paths written.
+func saveResults(result Result, config Config) (string, string, error) {
+	format, err := outputFormatOf(config)
+	if err != nil {
+		return "", "", err
+	}
+	timestamp := fileTimestamp(outputTime(config))
+	output := newResultOutput(result, config)
+
+	return writeOutputFiles(config.OutputDir, "solar_cooling_"+timestamp, format,
+		output, []ResultOutput{output})
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errDiskFull = errors.New("no space left on device")
+
+// failingWriter accepts limit bytes, then fails every write like a full
+// disk.
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errDiskFull
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestWriteErrorsPropagate(t *testing.T) {
+	config := defaultConfigWith(func(c *Config) { c.SolarReduction, c.ElectricityCost = 100, 0.15 })
+	output := resultOutputAt(calculateCoolingSavings(config), config, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	format, err := outputFormatOf(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		limit int
+		write func(w io.Writer) error
+		want  string
+	}{
+		{"CSV, nothing written", 0, func(w io.Writer) error { return writeCSV(w, []ResultOutput{output}, format) }, "failed to write CSV file"},
+		{"CSV, cut off partway", 400, func(w io.Writer) error { return writeCSV(w, []ResultOutput{output}, format) }, "failed to write CSV file"},
+		{"JSON, cut off partway", 100, func(w io.Writer) error { return renderJSON(w, output, false) }, "failed to write JSON file"},
+		{"compact JSON, nothing written", 0, func(w io.Writer) error { return renderJSON(w, output, true) }, "failed to write JSON file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.write(&failingWriter{tt.limit})
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), errDiskFull.Error()) {
+				t.Errorf("got %v, want %q with the disk error", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailedWriteLeavesNoFiles(t *testing.T) {
+	config := defaultConfigWith(func(c *Config) { c.SolarReduction, c.ElectricityCost = 100, 0.15 })
+	output := resultOutputAt(calculateCoolingSavings(config), config, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dir string, format *outputFormat)
+		left  []string // entries the setup itself made
+	}{
+		{"CSV write fails after the JSON", func(t *testing.T, dir string, format *outputFormat) {
+			format.Comma = '"' // refused by csv.Writer on the first row
+		}, nil},
+		{"gzip CSV write fails", func(t *testing.T, dir string, format *outputFormat) {
+			format.Comma, format.Gzip = '"', true
+		}, nil},
+		{"CSV rename fails after the JSON's", func(t *testing.T, dir string, format *outputFormat) {
+			if err := os.Mkdir(filepath.Join(dir, "result.csv"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}, []string{"result.csv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			format, err := outputFormatOf(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(t, dir, &format)
+			jsonPath, csvPath, err := writeOutputFiles(dir, "result", format, output, []ResultOutput{output})
+			if err == nil || !errors.Is(err, ErrWrite) {
+				t.Fatalf("writeOutputFiles = %v, want an ErrWrite", err)
+			}
+			if jsonPath != "" || csvPath != "" {
+				t.Errorf("paths %q and %q returned on failure", jsonPath, csvPath)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			if !slices.Equal(names, tt.left) {
+				t.Errorf("left %v in the output directory, want %v", names, tt.left)
+			}
+		})
+	}
+}
+
+func TestWriteOutputFilesReturnsTheFinalPaths(t *testing.T) {
+	config := defaultConfigWith(func(c *Config) { c.SolarReduction, c.ElectricityCost = 100, 0.15 })
+	output := resultOutputAt(calculateCoolingSavings(config), config, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	format, err := outputFormatOf(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonPath, csvPath, err := writeOutputFiles(dir, "result", format, output, []ResultOutput{output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonPath != filepath.Join(dir, "result.json") || csvPath != filepath.Join(dir, "result.csv") {
+		t.Errorf("paths %q and %q", jsonPath, csvPath)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("%d entries in the output directory, want the 2 files", len(entries))
+	}
+}
+
+func TestCheckOutputDir(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, root string) string // returns the directory to check
+		want  string                                 // error text, "" for none
+	}{
+		{"existing", func(t *testing.T, root string) string { return root }, ""},
+		{"created when missing", func(t *testing.T, root string) string { return filepath.Join(root, "a", "b") }, ""},
+		{"under a file", func(t *testing.T, root string) string {
+			file := filepath.Join(root, "file")
+			if err := os.WriteFile(file, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			return filepath.Join(file, "results")
+		}, "cannot create output directory"},
+		{"read-only", func(t *testing.T, root string) string {
+			if os.Geteuid() == 0 {
+				t.Skip("root can write to a read-only directory")
+			}
+			dir := filepath.Join(root, "readonly")
+			if err := os.Mkdir(dir, 0o555); err != nil {
+				t.Fatal(err)
+			}
+			return dir
+		}, "is not writable (permission denied); choose another with --output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.setup(t, t.TempDir())
+			err := checkOutputDir(dir)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+					t.Errorf("the check left %d entries in %s", len(entries), dir)
+				}
+				return
+			}
+			if err == nil || !errors.Is(err, ErrWrite) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("checkOutputDir = %v, want an ErrWrite saying %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalJSONIsStable(t *testing.T) {
+	config := defaultConfigWith(func(c *Config) {
+		c.SolarReduction, c.ElectricityCost, c.DumpIntermediates = 100, 0.15, true
+	})
+	result := calculateCoolingSavings(config)
+	result.Confidence = inputConfidence(config, map[string]string{})
+	output := resultOutputAt(result, config, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	many := make(map[string]float64)
+	for i := range 200 {
+		many[fmt.Sprintf("key_%03d", (i*37)%200)] = float64(i)
+	}
+	tests := []struct {
+		name   string
+		value  any
+		indent string
+		maps   []string // top-level keys holding objects whose keys must be sorted
+	}{
+		{"result", output, "  ", []string{"intermediates", "confidence"}},
+		{"compact result", output, "", []string{"intermediates", "confidence"}},
+		{"large map", map[string]any{"values": many}, "  ", []string{"values"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := canonicalJSON(tt.value, tt.indent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range 20 {
+				again, err := canonicalJSON(tt.value, tt.indent)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(first, again) {
+					t.Fatal("encoding the same value twice gave different bytes")
+				}
+			}
+			if indented := tt.indent != ""; indented != bytes.HasSuffix(first, []byte("}\n")) {
+				t.Errorf("final newline: %v, want %v", !indented, indented)
+			}
+
+			var decoded map[string]json.RawMessage
+			if err := json.Unmarshal(first, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.maps {
+				if len(decoded[key]) == 0 {
+					t.Errorf("%s is missing", key)
+				} else if err := checkSortedKeys(decoded[key]); err != nil {
+					t.Errorf("%s: %v", key, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// portfolioPeak is the peak reduction of a portfolio of buildings. The
+// buildings do not all peak at the same moment, so the reduction in the
+// combined peak is the sum of their peak reductions over the diversity
+// factor: the ratio of the sum of individual peaks to the coincident
+// peak, 1 for buildings that peak together and typically 1.1 to 1.5 for
+// clinics with different hours.
+type portfolioPeak struct {
+	Buildings                      int
+	SumElectricKW, SumTons         float64
+	DiversityFactor                float64
+	DiversifiedKW, DiversifiedTons float64
+}
+
+// add counts output's building and its peak reductions.
+func (p *portfolioPeak) add(output ResultOutput) {
+	p.Buildings++
+	p.SumElectricKW += output.PeakElectricityReduced
+	p.SumTons += output.PeakTonsReduced
+}
+
+// diversified returns p with the diversity factor applied to the sums.
+func (p portfolioPeak) diversified() portfolioPeak {
+	p.DiversifiedKW = p.SumElectricKW / p.DiversityFactor
+	p.DiversifiedTons = p.SumTons / p.DiversityFactor
+	return p
+}
+
+// printPortfolioPeak reports the naive and the diversified peak reduction.
+func printPortfolioPeak(w io.Writer, p portfolioPeak) {
+	fmt.Fprintf(w, "\nPortfolio peak reduction over %d buildings:\n", p.Buildings)
+	fmt.Fprintf(w, "Sum of building peaks: %.2f kW electric (%.2f tons)\n", p.SumElectricKW, p.SumTons)
+	fmt.Fprintf(w, "Diversified at factor %.2f: %.2f kW electric (%.2f tons)\n",
+		p.DiversityFactor, p.DiversifiedKW, p.DiversifiedTons)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// printResult writes the human-readable summary of result to w.
+func printResult(w io.Writer, result Result, config Config, verbose bool) {
+	heating := result.Mode == "heating"
+	// num formats a result, rounding to --sig-figs when it is set.
+	num := func(v float64, decimals int) string {
+		return formatFigures(v, decimals, config.SigFigs)
+	}
+	style := ansiStyle{colorEnabled(config.Color, w)}
+
+	fmt.Fprintf(w, "\nCalculation Results (Daily):\n")
+	if config.ScenarioName != "" {
+		fmt.Fprintf(w, "Scenario: %s\n", config.ScenarioName)
+	}
+	fmt.Fprintf(w, "Location: %s\n", result.Assumptions.Location)
+	fmt.Fprintf(w, "Building type: %s\n", result.Assumptions.BuildingType)
+
+	fmt.Fprintf(w, "\nInputs:\n")
+	if heating {
+		fmt.Fprintf(w, "Mode: heating (savings from added solar gain)\n")
+		fmt.Fprintf(w, "Total added solar radiation: %.2f %s\n",
+			-result.TotalSolarReduction,
+			result.Assumptions.Units.SolarRadiation)
+	} else {
+		fmt.Fprintf(w, "Total solar radiation reduction: %.2f %s\n",
+			result.TotalSolarReduction,
+			result.Assumptions.Units.SolarRadiation)
+	}
+	if config.CostHook != "" {
+		fmt.Fprintf(w, "Cost model: external hook %s\n", config.CostHook)
+	} else {
+		costLabel := "Electricity cost"
+		if heating {
+			costLabel = "Heating cost"
+		}
+		fmt.Fprintf(w, "%s: %.3f %s", costLabel,
+			result.Assumptions.ElectricityCost,
+			result.Assumptions.Units.Cost)
+		if result.Assumptions.ElectricityCostSource != "input" {
+			fmt.Fprintf(w, " (%s)", result.Assumptions.ElectricityCostSource)
+		}
+		fmt.Fprintln(w)
+	}
+	if config.PVOffsetFraction > 0 {
+		fmt.Fprintf(w, "PV offset fraction: %.2f\n", config.PVOffsetFraction)
+	}
+
+	if a := result.Assumptions; !heating && a.EffectiveCOP != a.AC_COP {
+		fmt.Fprintf(w, "Effective COP: %.2f (%s at %.0f%% load, rated %.1f)\n",
+			a.EffectiveCOP, config.SystemType, 100*config.LoadFraction, a.AC_COP)
+	}
+	if verbose {
+		if heating {
+			fmt.Fprintf(w, "Heating COP: %.2f\n", result.Assumptions.AC_COP)
+		} else {
+			fmt.Fprintf(w, "AC COP: %.1f\n", result.Assumptions.AC_COP)
+		}
+		fmt.Fprintf(w, "Solar Heat Gain Coefficient: %.2f\n", result.Assumptions.SHGC)
+		if config.WallArea > 0 {
+			fmt.Fprintf(w, "Window-to-Wall Ratio: %.2f over %.1f m² of wall\n", result.Assumptions.WWR, config.WallArea)
+		} else {
+			fmt.Fprintf(w, "Window-to-Wall Ratio: %.2f (informational without --wall-area)\n", result.Assumptions.WWR)
+		}
+		fmt.Fprintf(w, "Operating schedule: %.1f h/day, %.1f days/week\n",
+			result.Assumptions.OperatingHours, result.Assumptions.OperatingDays)
+	}
+
+	fmt.Fprintf(w, "\nResults:\n")
+	if heating {
+		fmt.Fprintf(w, "Total heating load reduced: %s %s\n",
+			num(result.CoolingLoadReduced, 2),
+			result.Assumptions.Units.CoolingLoad)
+		fmt.Fprintf(w, "Total heating energy saved: %s %s\n",
+			num(result.ElectricitySaved, 2),
+			result.Assumptions.Units.Electricity)
+	} else {
+		fmt.Fprintf(w, "Total cooling load reduced: %s %s\n",
+			num(result.CoolingLoadReduced, 2),
+			result.Assumptions.Units.CoolingLoad)
+		if result.ConductionLoadReduced > 0 {
+			fmt.Fprintf(w, "  Solar gain: %s %s\n", num(result.SolarLoadReduced, 2), result.Assumptions.Units.CoolingLoad)
+			fmt.Fprintf(w, "  Conduction (U-factor %.2f W/m²K better at %.1f K): %s %s\n",
+				config.UFactor, config.DeltaT, num(result.ConductionLoadReduced, 2), result.Assumptions.Units.CoolingLoad)
+		}
+		fmt.Fprintf(w, "Total electricity saved: %s %s\n",
+			num(result.ElectricitySaved, 2),
+			result.Assumptions.Units.Electricity)
+		if result.CompressorSaved > 0 {
+			fmt.Fprintf(w, "  Compressor only: %s %s (whole system with %.0f%% fan and pump losses above)\n",
+				num(result.CompressorSaved, 2), result.Assumptions.Units.Electricity, 100*config.SystemLosses)
+		}
+	}
+	if config.PVOffsetFraction > 0 {
+		fmt.Fprintf(w, "  Grid electricity displaced: %s %s\n",
+			num(result.GridElectricitySaved, 2),
+			result.Assumptions.Units.Electricity)
+		fmt.Fprintf(w, "  PV self-consumption freed: %s %s (not priced)\n",
+			num(result.SelfConsumptionSaved, 2),
+			result.Assumptions.Units.Electricity)
+	}
+	if !heating {
+		fmt.Fprintf(w, "Peak cooling load reduced: %s %s (%s tons)\n",
+			num(result.PeakCoolingReduced, 2),
+			result.Assumptions.Units.PeakCooling,
+			num(result.PeakTonsReduced, 2))
+		fmt.Fprintf(w, "Peak electrical demand reduced: %s %s\n",
+			num(result.PeakElectricityReduced, 2),
+			result.Assumptions.Units.PeakElectricity)
+	}
+	if verbose || result.OperatingFactor < 1 {
+		fmt.Fprintf(w, "Operating schedule factor: %.3f\n", result.OperatingFactor)
+	}
+	if result.TimeLagHours > 0 {
+		fmt.Fprintf(w, "Thermal mass: %s, %.0f h effective lag, %.0f%% of the gain released during operating hours\n",
+			config.ThermalMass, result.TimeLagHours, 100*result.Assumptions.TimeLagFactor)
+	}
+	if result.OperatingDaysPerYear > 0 {
+		fmt.Fprintf(w, "Effective operating days: %d per year (calendar %d)\n",
+			result.OperatingDaysPerYear, config.CalendarYear)
+	}
+	if config.EquipSchedule != "" && !heating {
+		weighting := "daytime 06:00-18:00"
+		if len(config.LoadShapeValues) == halfHours {
+			weighting = "the load shape"
+		}
+		fmt.Fprintf(w, "Medical equipment factor: %.3f effective from the schedule, weighted by %s\n",
+			result.Assumptions.MedicalEquipFactor, weighting)
+	}
+	if result.ShapedRate > 0 {
+		fmt.Fprintf(w, "Load-shape weighted rate: $%.4f/kWh (flat rate $%.4f/kWh)\n",
+			result.ShapedRate, config.ElectricityCost)
+	}
+	savingsLabel := "Annual cost savings"
+	if heating {
+		savingsLabel = "Annual heating cost savings"
+	} else if season := coolingSeason(config); season < 1 {
+		savingsLabel = fmt.Sprintf("Annual cost savings (cooling season, %.0f%% of the year)", 100*season)
+	}
+	fmt.Fprintf(w, "%s: %s %s\n", savingsLabel,
+		style.headline(num(result.AnnualCostSaved, 2)),
+		result.Assumptions.Units.Savings)
+	if result.HeatingPenalty > 0 {
+		fmt.Fprintf(w, "Heating penalty (winter gain blocked): -%s %s\n",
+			num(result.HeatingPenalty, 2), result.Assumptions.Units.Savings)
+		fmt.Fprintf(w, "Net annual savings: %s %s\n",
+			style.headline(num(result.NetAnnualSavings, 2)), result.Assumptions.Units.Savings)
+	}
+	if result.PercentOfBill > 0 {
+		scope := ""
+		if result.Roof != nil {
+			scope = " (with the cool roof)"
+		}
+		fmt.Fprintf(w, "Share of the $%.2f annual electricity bill%s: %s%%\n",
+			config.AnnualBill, scope, num(result.PercentOfBill, 1))
+	}
+	if in := result.Intensity; in != nil {
+		if in.GlazedArea > 0 {
+			fmt.Fprintf(w, "Savings intensity: %s $/m²/year, %s kWh/m²/year over %.1f m² of glazing\n",
+				num(in.CostPerGlazedArea, 2), num(in.EnergyPerGlazedArea, 1), in.GlazedArea)
+		}
+		if in.FloorArea > 0 {
+			fmt.Fprintf(w, "Savings intensity: %s $/m²/year, %s kWh/m²/year over %.1f m² of floor\n",
+				num(in.CostPerFloorArea, 2), num(in.EnergyPerFloorArea, 1), in.FloorArea)
+		}
+	}
+
+	if result.DRRevenue > 0 {
+		fmt.Fprintf(w, "Demand response: %s kW at the utility peak (coincidence %.2f) earns $%s/year at $%.2f/kW\n",
+			num(result.CoincidentDemand, 2), config.CoincidenceFactor, num(result.DRRevenue, 2), config.DRIncentive)
+	}
+
+	switch {
+	case math.IsInf(result.PaybackYears, 1):
+		fmt.Fprintf(w, "Simple payback: never, the $%.2f project saves nothing\n", config.ProjectCost)
+	case result.PaybackYears > 0:
+		fmt.Fprintf(w, "Simple payback: %s years on $%.2f\n", num(result.PaybackYears, 1), config.ProjectCost)
+	}
+
+	unit, _ := lookupCO2Unit(config.CO2Unit)
+	fmt.Fprintf(w, "CO2 avoided: %s %s/year at %.2f kg CO2e/kWh %s (%s)\n",
+		num(result.AnnualCO2Avoided*unit.PerKg, unit.Decimals), unit.Label,
+		result.GridCO2, result.EmissionsBasis, result.GridCO2Source)
+	if result.PercentEmissionsReduced > 0 {
+		fmt.Fprintf(w, "Share of the %s %s/year baseline emissions: %s%%\n",
+			num(config.BaselineEmissions*unit.PerKg, unit.Decimals), unit.Label, num(result.PercentEmissionsReduced, 1))
+	}
+	if result.CarbonValue > 0 {
+		fmt.Fprintf(w, "Carbon value: %s %s at $%.2f/tonne CO2e\n",
+			num(result.CarbonValue, 2), result.Assumptions.Units.Savings, config.CarbonPrice)
+		fmt.Fprintf(w, "Total annual benefit (net savings plus carbon value): %s %s\n",
+			style.headline(num(result.TotalAnnualBenefit, 2)), result.Assumptions.Units.Savings)
+	}
+
+	if result.CodeBaseline != nil {
+		fmt.Fprintf(w, "\n>> %s\n", codeBaselineSummary(result.CodeBaseline))
+		if verbose {
+			fmt.Fprintf(w, "A code-minimum retrofit alone would save %s %s\n",
+				num(result.CodeBaseline.AnnualCostSaved, 2), result.Assumptions.Units.Savings)
+		}
+	}
+
+	if b := result.Baseline; b != nil {
+		fmt.Fprintf(w, "\nAgainst the baseline %s ($%s/year net):\n", b.Path, num(b.BaselineNetSavings, 2))
+		fmt.Fprintf(w, "Net annual savings: %+.2f %s\n", b.NetSavings, result.Assumptions.Units.Savings)
+		fmt.Fprintf(w, "Electricity saved: %+.2f %s\n", b.ElectricitySaved, result.Assumptions.Units.Electricity)
+		fmt.Fprintf(w, "Peak electrical demand reduced: %+.2f %s\n",
+			b.PeakElectricityReduced, result.Assumptions.Units.PeakElectricity)
+		fmt.Fprintf(w, "CO2 avoided: %+.1f kg/year\n", b.AnnualCO2Avoided)
+	}
+
+	if result.Sizing != nil {
+		fmt.Fprintf(w, "\nEquipment sizing (advisory): the next cooling unit could be %s %s (%s tons) smaller\n",
+			num(result.Sizing.CapacityReducedKW, 2), result.Assumptions.Units.PeakCooling,
+			num(result.Sizing.CapacityReducedTon, 2))
+		if verbose {
+			fmt.Fprintf(w, "Peak reduction x %.2f sizing safety factor; confirm with a full load calculation\n",
+				result.Sizing.SafetyFactor)
+		}
+	}
+
+	if note := zeroSavingsNote(result, config); note != "" {
+		fmt.Fprintf(w, "\nNote: %s\n", note)
+	}
+
+	if defaulted := defaultedInputs(result.Confidence); len(defaulted)*2 > len(result.Confidence) {
+		fmt.Fprintf(w, "\nNote: %d of %d inputs are unconfirmed defaults; treat these results as indicative\n",
+			len(defaulted), len(result.Confidence))
+	}
+
+	if config.StartDate != "" {
+		fmt.Fprintf(w, "\nDelaying this retrofit has cost $%s so far (%d days since %s)\n",
+			num(result.ForegoneSavings, 2), result.DaysDelayed, config.StartDate)
+	}
+
+	if len(result.WindowGroups) > 0 {
+		fmt.Fprintf(w, "\nWindow Groups:\n")
+		if verbose {
+			latitude := facadeLatitude(config)
+			multipliers := make([]string, len(orientations))
+			for i, o := range orientations {
+				m, _ := orientationMultiplier(o, latitude)
+				multipliers[i] = fmt.Sprintf("%s %.2f", o, m)
+			}
+			fmt.Fprintf(w, "Facade irradiance multipliers at latitude %.1f: %s\n",
+				latitude, strings.Join(multipliers, ", "))
+		}
+		for i, g := range result.WindowGroups {
+			name := g.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			fmt.Fprintf(w, "%s (%s, %.1f m², SHGC %.2f): %s %s cooling, %s %s\n",
+				name, g.Orientation, g.Area, g.SHGC,
+				num(g.CoolingLoadReduced, 2), result.Assumptions.Units.CoolingLoad,
+				num(g.AnnualCostSaved, 2), result.Assumptions.Units.Savings)
+		}
+	}
+
+	if len(result.Monthly) > 0 {
+		fmt.Fprintf(w, "\nWindow Savings by Month (weighted by cooling degree days):\n")
+		fmt.Fprintf(w, "%-5s %8s %12s %10s\n", "Month", "CDD", "kWh", "$")
+		for _, m := range result.Monthly {
+			fmt.Fprintf(w, "%-5s %8.0f %12s %10s\n", m.Month, m.CDD, num(m.ElectricitySaved, 0), num(m.CostSaved, 2))
+		}
+	}
+
+	if result.Roof != nil {
+		fmt.Fprintf(w, "\nCool Roof (opaque envelope):\n")
+		if verbose {
+			fmt.Fprintf(w, "Roof area: %.1f m², U-factor %.2f W/m²K\n", result.Roof.Area, result.Roof.UFactor)
+			fmt.Fprintf(w, "Absorptance: %.2f -> %.2f at %.2f kWh/m²/day\n",
+				result.Roof.Absorptance, result.Roof.ProposedAbsorptance, result.Roof.Irradiance)
+		}
+		fmt.Fprintf(w, "Roof cooling load reduced: %s %s\n",
+			num(result.Roof.CoolingLoadReduced, 2),
+			result.Assumptions.Units.CoolingLoad)
+		fmt.Fprintf(w, "Roof electricity saved: %s %s\n",
+			num(result.Roof.ElectricitySaved, 2),
+			result.Assumptions.Units.Electricity)
+		fmt.Fprintf(w, "Roof annual cost savings: %s %s\n",
+			num(result.Roof.AnnualCostSaved, 2),
+			result.Assumptions.Units.Savings)
+		fmt.Fprintf(w, "Combined annual cost savings: %s %s\n",
+			num(result.AnnualCostSaved+result.Roof.AnnualCostSaved, 2),
+			result.Assumptions.Units.Savings)
+	}
+
+	if verbose {
+		fmt.Fprintf(w, "\nDetailed Assumptions:\n")
+		fmt.Fprintf(w, "Transmission Factor: %.2f\n", result.Assumptions.TransmissionFactor)
+		fmt.Fprintf(w, "Time Lag Factor: %.2f\n", result.Assumptions.TimeLagFactor)
+		fmt.Fprintf(w, "Medical Equipment Factor: %.2f\n", result.Assumptions.MedicalEquipFactor)
+		if len(result.Confidence) > 0 {
+			fmt.Fprintf(w, "\nInput Confidence:\n")
+			for _, key := range confidenceInputs {
+				fmt.Fprintf(w, "%s: %s\n", key, result.Confidence[key])
+			}
+		}
+		fmt.Fprintf(w, "\nReproduce with: %s\n", reproduceCommand(config))
+	}
+}
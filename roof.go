@@ -0,0 +1,45 @@
+package main
+
+// outsideFilmCoefficient is the exterior surface heat transfer coefficient
+// in W/m²K used for sol-air temperature (ASHRAE Fundamentals, summer).
+const outsideFilmCoefficient = 17.0
+
+// RoofResult is the opaque-envelope component for a cool-roof retrofit,
+// reported separately from the window-driven reduction.
+type RoofResult struct {
+	Area                float64 `json:"area_m2"`
+	Absorptance         float64 `json:"absorptance"`
+	ProposedAbsorptance float64 `json:"proposed_absorptance"`
+	UFactor             float64 `json:"u_factor_w_m2k"`
+	Irradiance          float64 `json:"irradiance_kwh_m2_day"`
+	CoolingLoadReduced  float64 `json:"cooling_load_reduced_kwh_day"`
+	ElectricitySaved    float64 `json:"electricity_saved_kwh_day"`
+	AnnualCostSaved     float64 `json:"annual_cost_saved_usd"`
+}
+
+// calculateRoofSavings estimates the conducted solar gain avoided by
+// lowering roof absorptance. The sol-air excess temperature is α·I/h_o,
+// so the daily conducted gain is U·A·α·H/h_o for daily irradiation H. Like
+// the windows', the roof's savings accrue over the coolingSeason only.
+func calculateRoofSavings(config Config, irradiance float64) *RoofResult {
+	if config.RoofArea <= 0 {
+		return nil
+	}
+
+	deltaAbsorptance := config.RoofAbsorptance - config.RoofAbsorptanceProposed
+	coolingLoadReduced := config.RoofUFactor * config.RoofArea *
+		deltaAbsorptance * irradiance / outsideFilmCoefficient
+
+	electricitySaved := coolingLoadReduced / systemCOP(config)
+
+	return &RoofResult{
+		Area:                config.RoofArea,
+		Absorptance:         config.RoofAbsorptance,
+		ProposedAbsorptance: config.RoofAbsorptanceProposed,
+		UFactor:             config.RoofUFactor,
+		Irradiance:          irradiance,
+		CoolingLoadReduced:  coolingLoadReduced,
+		ElectricitySaved:    electricitySaved,
+		AnnualCostSaved:     electricitySaved * gridFraction(config) * config.ElectricityCost * 365 * scheduleFactor(config) * coolingSeason(config),
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// sanityCheck compares one input with the range typical for a clinic.
+// It is guidance for catching unit and order-of-magnitude mistakes, not
+// validation: an input outside the range may still be right.
+type sanityCheck struct {
+	Input     string
+	Value     float64
+	Low, High float64 // typical range
+	Unit      string
+	Note      string // what the value implies, or the likely mistake
+}
+
+// typical reports whether the value is inside the typical range.
+func (c sanityCheck) typical() bool {
+	return c.Value >= c.Low && c.Value <= c.High
+}
+
+// sanityChecks builds the --sanity report for config, with ghi the
+// horizontal irradiance in kWh/m²/day used to translate the reduction
+// into glazed area.
+func sanityChecks(config Config, ghi float64) []sanityCheck {
+	reduction := math.Abs(config.SolarReduction)
+	area := sanityCheck{Input: "solar_reduction", Value: reduction, Unit: "kWh/day",
+		Low: 10 * ghi * 0.5, High: 300 * ghi}
+	if ghi > 0 {
+		area.Note = fmt.Sprintf("implies about %.0f m² of glazing fully shaded at %.2f kWh/m²/day; clinics typically have 10-300 m²",
+			reduction/ghi, ghi)
+	}
+	if reduction > area.High {
+		area.Note += fmt.Sprintf("; if this is kWh/year, the daily value is %.1f", reduction/365)
+	}
+
+	cost := sanityCheck{Input: "electricity_cost", Value: config.ElectricityCost, Unit: "$/kWh", Low: 0.06, High: 0.45}
+	if config.ElectricityCost > 1 {
+		cost.Note = fmt.Sprintf("looks like cents; $%.3f/kWh is %.0f¢", config.ElectricityCost/100, config.ElectricityCost)
+	}
+
+	cop := sanityCheck{Input: "ac_cop", Value: config.AC_COP, Unit: "", Low: 2.5, High: 6}
+	switch {
+	case config.AC_COP >= 8:
+		cop.Note = fmt.Sprintf("looks like an EER or SEER; as a COP, EER %.1f is %.2f", config.AC_COP, config.AC_COP/3.412)
+	case config.AC_COP < 1.5:
+		cop.Note = "below 1.5 is unusual for vapor-compression cooling; kW/ton is not a COP (COP = 3.517 / kW/ton)"
+	}
+
+	checks := []sanityCheck{
+		area, cost, cop,
+		{Input: "shgc", Value: config.SHGC, Low: 0.2, High: 0.7},
+		{Input: "wwr", Value: config.WWR, Low: 0.1, High: 0.6},
+		{Input: "operating_hours", Value: config.OperatingHours, Unit: "h/day", Low: 8, High: 24},
+	}
+	if config.CostHook != "" || config.HeatingMode {
+		// The electricity cost is not what prices this run.
+		checks = append(checks[:1], checks[2:]...)
+	}
+	return checks
+}
+
+// sanityNumber shows v to three significant figures without trailing
+// zeros; the ranges are rough, so more would be false precision.
+func sanityNumber(v float64) string {
+	return strconv.FormatFloat(roundSigFigs(v, 3), 'f', -1, 64)
+}
+
+// printSanity writes the --sanity report.
+func printSanity(w io.Writer, checks []sanityCheck) {
+	fmt.Fprintf(w, "%-17s %10s %-8s %-17s %s\n", "Input", "Value", "Unit", "Typical", "")
+	unusual := 0
+	for _, c := range checks {
+		status := "ok"
+		if !c.typical() {
+			status = "UNUSUAL"
+			unusual++
+		}
+		fmt.Fprintf(w, "%-17s %10s %-8s %-17s %s\n", c.Input, sanityNumber(c.Value), c.Unit,
+			sanityNumber(c.Low)+"-"+sanityNumber(c.High), status)
+		if c.Note != "" {
+			fmt.Fprintf(w, "  %s\n", c.Note)
+		}
+	}
+	if unusual > 0 {
+		fmt.Fprintf(w, "\n%d input(s) fall outside the typical range; check their units before trusting the results\n", unusual)
+	} else {
+		fmt.Fprintln(w, "\nAll inputs are in their typical ranges")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// runSchema is the schema command: print the JSON Schema of the config
+// file or of the result JSON files.
+func runSchema(args []string) int {
+	fs := pflag.NewFlagSet("schema", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  calculator schema [config|result]\n\n")
+		fmt.Fprintf(os.Stderr, "Prints the JSON Schema of the config file or of the result JSON (the default).\n")
+	}
+	fs.Parse(args)
+
+	kind := "result"
+	if fs.NArg() > 0 {
+		kind = fs.Arg(0)
+	}
+
+	var schema map[string]any
+	switch {
+	case fs.NArg() > 1:
+		fs.Usage()
+		return 2
+	case kind == "config":
+		schema = jsonSchema(reflect.TypeOf(Config{}))
+		// Every config key is optional and falls back to its default.
+		delete(schema, "required")
+		schema["additionalProperties"] = false
+	case kind == "result":
+		schema = jsonSchema(reflect.TypeOf(ResultOutput{}))
+		schema["properties"].(map[string]any)["schema_version"] = map[string]any{"const": outputSchemaVersion}
+	default:
+		fmt.Printf("Error: unknown schema %q, expected config or result\n", kind)
+		return 2
+	}
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(schema); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// jsonSchema describes how encoding/json marshals t. Fields without
+// omitempty are required; embedded structs contribute their fields.
+func jsonSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		required := []string{}
+		addStructFields(t, properties, &required)
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	}
+	return map[string]any{}
+}
+
+func addStructFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			addStructFields(field.Type, properties, required)
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = jsonSchema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
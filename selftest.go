@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// selftestTolerance is the relative error allowed against the expected
+// values, loose enough for differing float rounding across platforms.
+const selftestTolerance = 1e-9
+
+// selftestCase is a known input and the values it must produce.
+type selftestCase struct {
+	name   string
+	config func(c *Config)
+	check  func(r Result) map[string][2]float64 // quantity: {got, want}
+}
+
+// selftestCases were worked by hand from the formulas in
+// calculateCoolingSavings, calculateHeatingSavings and
+// calculateRoofSavings.
+var selftestCases = []selftestCase{
+	{
+		name:   "defaults, 100 kWh/day at $0.15",
+		config: func(c *Config) { c.SolarReduction, c.ElectricityCost = 100, 0.15 },
+		check: func(r Result) map[string][2]float64 {
+			return map[string][2]float64{
+				"cooling load":    {r.CoolingLoadReduced, 21.85},
+				"electricity":     {r.ElectricitySaved, 5.4625},
+				"peak cooling kW": {r.PeakCoolingReduced, 2.73125},
+				"peak tons":       {r.PeakTonsReduced, 2.73125 / 3.517},
+				"annual savings":  {r.AnnualCostSaved, 299.071875},
+			}
+		},
+	},
+	{
+		name: "README example, COP 3.5 and SHGC 0.3",
+		config: func(c *Config) {
+			c.SolarReduction, c.ElectricityCost, c.AC_COP, c.SHGC = 150.5, 0.12, 3.5, 0.3
+		},
+		check: func(r Result) map[string][2]float64 {
+			return map[string][2]float64{
+				"cooling load":   {r.CoolingLoadReduced, 39.4611},
+				"electricity":    {r.ElectricitySaved, 39.4611 / 3.5},
+				"annual savings": {r.AnnualCostSaved, 39.4611 / 3.5 * 0.12 * 365},
+			}
+		},
+	},
+	{
+		name: "12 h/day, 5 days/week schedule",
+		config: func(c *Config) {
+			c.SolarReduction, c.ElectricityCost, c.OperatingHours, c.OperatingDays = 100, 0.15, 12, 5
+		},
+		check: func(r Result) map[string][2]float64 {
+			return map[string][2]float64{
+				"operating factor": {r.OperatingFactor, 0.5 * 5 / 7},
+				"peak cooling kW":  {r.PeakCoolingReduced, 2.73125},
+				"annual savings":   {r.AnnualCostSaved, 299.071875 * 0.5 * 5 / 7},
+			}
+		},
+	},
+	{
+		name: "half the cooling met by PV",
+		config: func(c *Config) {
+			c.SolarReduction, c.ElectricityCost, c.PVOffsetFraction = 100, 0.15, 0.5
+		},
+		check: func(r Result) map[string][2]float64 {
+			return map[string][2]float64{
+				"grid electricity": {r.GridElectricitySaved, 2.73125},
+				"annual savings":   {r.AnnualCostSaved, 149.5359375},
+			}
+		},
+	},
+	{
+		name: "heating mode, 100 kWh/day added gain",
+		config: func(c *Config) {
+			c.HeatingMode, c.SolarReduction, c.ElectricityCost = true, -100, 0.10
+		},
+		check: func(r Result) map[string][2]float64 {
+			return map[string][2]float64{
+				"heating load":   {r.CoolingLoadReduced, 19},
+				"heating energy": {r.ElectricitySaved, 19.0 / 3},
+				"annual savings": {r.AnnualCostSaved, 19.0 / 3 * 0.10 * 365},
+			}
+		},
+	},
+	{
+		name: "cool roof, 100 m² at 5.1 kWh/m²/day",
+		config: func(c *Config) {
+			c.SolarReduction, c.ElectricityCost, c.RoofArea = 100, 0.15, 100
+		},
+		check: func(r Result) map[string][2]float64 {
+			roof := calculateRoofSavings(defaultConfigWith(func(c *Config) {
+				c.ElectricityCost, c.RoofArea = 0.15, 100
+			}), 5.1)
+			return map[string][2]float64{
+				"roof cooling load": {roof.CoolingLoadReduced, 1.881},
+				"roof electricity":  {roof.ElectricitySaved, 0.47025},
+				"roof savings":      {roof.AnnualCostSaved, 0.47025 * 0.15 * 365},
+			}
+		},
+	},
+}
+
+// defaultConfigWith returns DefaultConfig modified by set.
+func defaultConfigWith(set func(c *Config)) Config {
+	config := DefaultConfig()
+	set(&config)
+	return config
+}
+
+// runSelftest is the selftest command: run the known cases and report
+// pass or fail for each, exiting non-zero on any mismatch.
+func runSelftest(args []string) int {
+	fs := pflag.NewFlagSet("selftest", pflag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  calculator selftest\n\n")
+		fmt.Fprintf(os.Stderr, "Checks the calculation against known results on this machine.\n")
+	}
+	fs.Parse(args)
+
+	failed := 0
+	for _, tc := range selftestCases {
+		result := calculateCoolingSavings(defaultConfigWith(tc.config))
+		var mismatches []string
+		for quantity, values := range tc.check(result) {
+			got, want := values[0], values[1]
+			if math.Abs(got-want) > selftestTolerance*math.Max(1, math.Abs(want)) {
+				mismatches = append(mismatches, fmt.Sprintf("%s = %.12g, want %.12g", quantity, got, want))
+			}
+		}
+		if len(mismatches) == 0 {
+			fmt.Printf("PASS  %s\n", tc.name)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL  %s\n", tc.name)
+		for _, m := range mismatches {
+			fmt.Printf("        %s\n", m)
+		}
+	}
+
+	if err := checkCanonicalJSON(); err != nil {
+		failed++
+		fmt.Printf("FAIL  result JSON is canonical\n        %v\n", err)
+	} else {
+		fmt.Printf("PASS  result JSON is canonical\n")
+	}
+
+	if err := checkWWR(); err != nil {
+		failed++
+		fmt.Printf("FAIL  WWR scales the savings over a wall area only\n        %v\n", err)
+	} else {
+		fmt.Printf("PASS  WWR scales the savings over a wall area only\n")
+	}
+
+	total := len(selftestCases) + 2
+	if failed > 0 {
+		fmt.Printf("\n%d of %d checks failed\n", failed, total)
+		return 1
+	}
+	fmt.Printf("\nAll %d checks passed\n", total)
+	return 0
+}
+
+// checkCanonicalJSON encodes a result with every map field populated
+// repeatedly, checking the bytes never change and the keys of each
+// object are in order.
+func checkCanonicalJSON() error {
+	config := defaultConfigWith(func(c *Config) {
+		c.SolarReduction, c.ElectricityCost, c.DumpIntermediates = 100, 0.15, true
+	})
+	result := calculateCoolingSavings(config)
+	result.Confidence = inputConfidence(config, map[string]string{})
+	output := resultOutputAt(result, config, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	first, err := canonicalJSON(output, "  ")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 20; i++ {
+		again, err := canonicalJSON(output, "  ")
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(first, again) {
+			return errors.New("encoding the same result twice gave different bytes")
+		}
+	}
+
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(first, &decoded); err != nil {
+		return err
+	}
+	for _, key := range []string{"intermediates", "confidence"} {
+		if err := checkSortedKeys(decoded[key]); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// checkWWR estimates and computes scenarios that differ only in WWR at a
+// fixed 5.1 kWh/m²/day. With a given reduction the WWR is informational;
+// over a wall area it sets the window area, so doubling it doubles the
+// savings.
+func checkWWR() error {
+	solar := func() (SolarResource, error) {
+		return SolarResource{AnnualGHI: 5.1, Source: "selftest"}, nil
+	}
+	savings := func(set func(c *Config)) (float64, error) {
+		config := defaultConfigWith(set)
+		if err := estimateInputs(&config, nil, solar, io.Discard); err != nil {
+			return 0, err
+		}
+		result, err := computeResult(config, solar)
+		return result.AnnualCostSaved, err
+	}
+
+	var got [4]float64
+	for i, set := range []func(c *Config){
+		func(c *Config) { c.SolarReduction, c.ElectricityCost, c.WWR = 100, 0.15, 0.2 },
+		func(c *Config) { c.SolarReduction, c.ElectricityCost, c.WWR = 100, 0.15, 0.4 },
+		func(c *Config) { c.WallArea, c.ElectricityCost, c.WWR = 100, 0.15, 0.2 },
+		func(c *Config) { c.WallArea, c.ElectricityCost, c.WWR = 100, 0.15, 0.4 },
+	} {
+		var err error
+		if got[i], err = savings(set); err != nil {
+			return err
+		}
+	}
+	if got[0] != got[1] {
+		return fmt.Errorf("with --reduction, WWR 0.2 saves %.12g and WWR 0.4 %.12g, want equal", got[0], got[1])
+	}
+	if math.Abs(got[3]-2*got[2]) > selftestTolerance*got[3] {
+		return fmt.Errorf("over 100 m² of wall, WWR 0.2 saves %.12g and WWR 0.4 %.12g, want double", got[2], got[3])
+	}
+	return nil
+}
+
+// checkSortedKeys reports whether the JSON object's keys appear sorted.
+func checkSortedKeys(object json.RawMessage) error {
+	decoder := json.NewDecoder(bytes.NewReader(object))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	previous := ""
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		if key < previous {
+			return fmt.Errorf("key %q follows %q", key, previous)
+		}
+		previous = key
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
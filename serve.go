@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// maxRequestBytes caps the size of a /calculate request body.
+const maxRequestBytes = 1 << 20
+
+// maxBatchScenarios caps the number of scenarios in one /calculate/batch
+// request.
+const maxBatchScenarios = 500
+
+// batchItem is one entry of a /calculate/batch response: the result, or
+// the errors that kept the scenario at Index from being calculated.
+type batchItem struct {
+	Index  int           `json:"index"`
+	Result *ResultOutput `json:"result,omitempty"`
+	Errors []string      `json:"errors,omitempty"`
+}
+
+// runServe is the serve command: an HTTP API over the same model, with
+// the configuration from --config and flags as the base for every request.
+func runServe(args []string) int {
+	config := DefaultConfig()
+	var addr string
+	var withMetrics bool
+	var auditPath string
+
+	fs := pflag.NewFlagSet("serve", pflag.ExitOnError)
+	flags := bindConfigFlags(fs, &config)
+	fs.StringVar(&addr, "addr", "localhost:8080",
+		"Address to listen on")
+	fs.BoolVar(&withMetrics, "metrics", false,
+		"Expose Prometheus metrics at /metrics")
+	bindAuditFlag(fs, &auditPath)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  calculator serve [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "POST /calculate takes a JSON object of config file keys and returns the\n")
+		fmt.Fprintf(os.Stderr, "result JSON. Keys not given fall back to --config and flags. Keys naming a\n")
+		fmt.Fprintf(os.Stderr, "file or program on the server (%s) are refused.\n", strings.Join(operatorOnlyKeys, ", "))
+		fmt.Fprintf(os.Stderr, "POST /calculate/batch takes an array of such objects (at most %d) and returns\n", maxBatchScenarios)
+		fmt.Fprintf(os.Stderr, "an array of {index, result} or {index, errors}, one per scenario.\n")
+		fmt.Fprintf(os.Stderr, "Send Accept: text/csv for the CSV rendering instead; a batch then lists the\n")
+		fmt.Fprintf(os.Stderr, "indexes of failed scenarios in X-Failed-Scenarios. Errors are always JSON.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	flags.record()
+	fileKeys, err := flags.load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	openAuditLog(auditPath, "serve")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var metrics *serveMetrics
+	if withMetrics {
+		metrics = newServeMetrics()
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newServeMux(config, flags.sources(fileKeys), metrics),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newServeMux routes the HTTP API. base and sources describe the
+// configuration each request overrides. /metrics is served only when
+// metrics is non-nil.
+func newServeMux(base Config, sources map[string]string, metrics *serveMetrics) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	if metrics != nil {
+		mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			metrics.writeTo(w)
+		})
+	}
+	mux.HandleFunc("/calculate", metrics.instrument("/calculate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		scenario, requestSources, err := decodeScenario(r.Body, base, sources)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		start := time.Now()
+		result, scenario, err := runScenario(scenario, requestSources, io.Discard)
+		metrics.observe(time.Since(start))
+		if err != nil {
+			metrics.validationFailed("/calculate")
+			writeJSONError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		output := newResultOutput(result, scenario)
+		writeResults(w, r, scenario, output, []ResultOutput{output})
+	}))
+	mux.HandleFunc("/calculate/batch", metrics.instrument("/calculate/batch", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		items, err := runBatchRequest(r.Body, base, sources, metrics)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		var outputs []ResultOutput
+		var failed []string
+		for _, item := range items {
+			if item.Result != nil {
+				outputs = append(outputs, *item.Result)
+			} else {
+				failed = append(failed, strconv.Itoa(item.Index))
+			}
+		}
+		if len(failed) > 0 {
+			w.Header().Set("X-Failed-Scenarios", strings.Join(failed, ","))
+		}
+		writeResults(w, r, base, items, outputs)
+	}))
+	return mux
+}
+
+// operatorOnlyKeys are the config keys naming a program or file on the
+// server. A request could otherwise run any program or have any file
+// read and its parse errors echoed back, so only the operator may set
+// them.
+var operatorOnlyKeys = []string{"cost_hook", "eplus_csv", "load_shape", "monthly_cdd", "holidays"}
+
+// decodeScenario applies a request body of config file keys over base,
+// marking the keys it sets as coming from the request. encoding/json
+// matches keys case-insensitively, so they are compared the same way.
+func decodeScenario(body io.Reader, base Config, sources map[string]string) (Config, map[string]string, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxRequestBytes+1))
+	if err != nil {
+		return base, nil, fmt.Errorf("failed to read request: %v", err)
+	}
+	if len(data) > maxRequestBytes {
+		return base, nil, errors.New("request body too large")
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return base, nil, markError(ErrConfigParse, fmt.Errorf("invalid request: %v", err))
+	}
+	for key := range keys {
+		for _, operatorKey := range operatorOnlyKeys {
+			if strings.EqualFold(key, operatorKey) {
+				return base, nil, fmt.Errorf("%s cannot be set by a request", operatorKey)
+			}
+		}
+	}
+
+	scenario := base
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&scenario); err != nil {
+		return base, nil, markError(ErrConfigParse, fmt.Errorf("invalid request: %v", err))
+	}
+	// Whatever spelling reached them, the operator's settings must stand.
+	for _, key := range operatorOnlyKeys {
+		got, _ := configField(&scenario, key)
+		want, _ := configField(&base, key)
+		if got.Interface() != want.Interface() {
+			return base, nil, fmt.Errorf("%s cannot be set by a request", key)
+		}
+	}
+
+	requestSources := make(map[string]string, len(sources))
+	for key, source := range sources {
+		requestSources[key] = source
+	}
+	for key := range keys {
+		for _, field := range configFields {
+			if strings.EqualFold(key, field.Key) {
+				key = field.Key
+				break
+			}
+		}
+		requestSources[key] = "request"
+	}
+	return scenario, requestSources, nil
+}
+
+// runBatchRequest calculates each scenario of a JSON array body. Only a
+// malformed or oversized array fails the request; a scenario that cannot
+// be decoded or calculated reports its errors in its own item.
+func runBatchRequest(body io.Reader, base Config, sources map[string]string, metrics *serveMetrics) ([]batchItem, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxRequestBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request: %v", err)
+	}
+	if len(data) > maxRequestBytes {
+		return nil, errors.New("request body too large")
+	}
+	var scenarios []json.RawMessage
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("invalid request: expected an array of scenarios: %v", err)
+	}
+	if len(scenarios) > maxBatchScenarios {
+		return nil, fmt.Errorf("too many scenarios: %d, the limit is %d", len(scenarios), maxBatchScenarios)
+	}
+
+	items := make([]batchItem, len(scenarios))
+	for i, raw := range scenarios {
+		items[i].Index = i
+		scenario, requestSources, err := decodeScenario(bytes.NewReader(raw), base, sources)
+		var result Result
+		if err == nil {
+			start := time.Now()
+			result, scenario, err = runScenario(scenario, requestSources, io.Discard)
+			metrics.observe(time.Since(start))
+		}
+		if err != nil {
+			metrics.validationFailed("/calculate/batch")
+			items[i].Errors = strings.Split(err.Error(), "\n")
+			continue
+		}
+		output := newResultOutput(result, scenario)
+		items[i].Result = &output
+	}
+	return items, nil
+}
+
+// negotiateCSV reports whether the request's Accept header prefers
+// text/csv over application/json. A missing header, or one that ranks
+// them equally, gets JSON; ok is false if neither is acceptable.
+func negotiateCSV(r *http.Request) (csv bool, ok bool) {
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return false, true
+	}
+	var jsonQ, csvQ float64
+	for _, part := range strings.Split(header, ",") {
+		media, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if text, found := params["q"]; found {
+			if q, err = strconv.ParseFloat(text, 64); err != nil {
+				continue
+			}
+		}
+		switch media {
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		case "text/csv":
+			csvQ = max(csvQ, q)
+		case "application/*":
+			jsonQ = max(jsonQ, q)
+		case "text/*":
+			csvQ = max(csvQ, q)
+		case "*/*":
+			jsonQ, csvQ = max(jsonQ, q), max(csvQ, q)
+		}
+	}
+	return csvQ > jsonQ, jsonQ > 0 || csvQ > 0
+}
+
+// writeResults responds with jsonValue, or with outputs rendered as CSV
+// in config's output format when the client asks for text/csv.
+func writeResults(w http.ResponseWriter, r *http.Request, config Config, jsonValue any, outputs []ResultOutput) {
+	w.Header().Add("Vary", "Accept")
+	wantCSV, ok := negotiateCSV(r)
+	if !ok {
+		writeJSONError(w, http.StatusNotAcceptable, errors.New("results are available as application/json or text/csv"))
+		return
+	}
+	if !wantCSV {
+		writeJSON(w, http.StatusOK, jsonValue)
+		return
+	}
+	format, err := outputFormatOf(config)
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, outputs, format); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeJSONError reports err as {"errors": [...]}, one entry per joined
+// error.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string][]string{"errors": strings.Split(err.Error(), "\n")})
+}
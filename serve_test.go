@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeRefusesOperatorOnlyKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string // error text, "" to accept
+	}{
+		{"plain request", `{"solar_reduction": 100, "electricity_cost": 0.15}`, ""},
+		{"mixed-case input", `{"SOLAR_REDUCTION": 100, "Electricity_Cost": 0.15}`, ""},
+		{"cost hook", `{"cost_hook": "/bin/echo"}`, "cost_hook cannot be set by a request"},
+		{"upper-case cost hook", `{"COST_HOOK": "/bin/echo"}`, "cost_hook cannot be set by a request"},
+		{"mixed-case cost hook", `{"Cost_Hook": "/bin/echo"}`, "cost_hook cannot be set by a request"},
+		{"upper-case EnergyPlus CSV", `{"solar_reduction": 100, "EPLUS_CSV": "/etc/passwd"}`, "eplus_csv cannot be set by a request"},
+		{"mixed-case load shape", `{"Load_Shape": "/etc/passwd"}`, "load_shape cannot be set by a request"},
+		{"upper-case monthly CDD", `{"MONTHLY_CDD": "/etc/passwd"}`, "monthly_cdd cannot be set by a request"},
+		{"mixed-case holidays", `{"hoLIDays": "/etc/passwd"}`, "holidays cannot be set by a request"},
+	}
+	base := defaultConfigWith(func(c *Config) { c.OutputDir = t.TempDir() })
+	server := httptest.NewServer(newServeMux(base, map[string]string{}, nil))
+	defer server.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario, sources, err := decodeScenario(strings.NewReader(tt.body), base, map[string]string{})
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if scenario.SolarReduction != 100 || sources["solar_reduction"] != "request" {
+					t.Errorf("reduction %g from %q, want 100 from the request",
+						scenario.SolarReduction, sources["solar_reduction"])
+				}
+			} else if err == nil || err.Error() != tt.want {
+				t.Errorf("decodeScenario = %v, want %q", err, tt.want)
+			}
+
+			resp, err := http.Post(server.URL+"/calculate", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if refused := resp.StatusCode == http.StatusBadRequest; refused != (tt.want != "") {
+				t.Errorf("POST /calculate: status %s, want refused: %v", resp.Status, tt.want != "")
+			}
+
+			resp, err = http.Post(server.URL+"/calculate/batch", "application/json", strings.NewReader("["+tt.body+"]"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if refused := strings.Contains(string(body), "cannot be set by a request"); refused != (tt.want != "") {
+				t.Errorf("POST /calculate/batch: %s, want refused: %v", body, tt.want != "")
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pricePoint is one electricity price of a --shadow-price table, labelled
+// by the year it is projected for or, for a range, by the price itself.
+type pricePoint struct {
+	Label string
+	Price float64 // $/kWh
+}
+
+// parsePricePoints reads --shadow-price: either labelled prices such as
+// 2025=0.15,2030=0.19, or a range start:stop:step of $/kWh.
+func parsePricePoints(spec string) ([]pricePoint, error) {
+	if !strings.Contains(spec, "=") {
+		axis, err := parseTableAxis("electricity_cost=" + spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --shadow-price %q: expected start:stop:step or year=price,...", spec)
+		}
+		points := make([]pricePoint, len(axis.Values))
+		for i, price := range axis.Values {
+			points[i] = pricePoint{strconv.FormatFloat(price, 'g', -1, 64), price}
+		}
+		return points, nil
+	}
+	var points []pricePoint
+	for _, item := range strings.Split(spec, ",") {
+		label, text, ok := strings.Cut(item, "=")
+		price, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if !ok || strings.TrimSpace(label) == "" || err != nil {
+			return nil, fmt.Errorf("invalid --shadow-price entry %q: expected year=price", item)
+		}
+		if price <= 0 {
+			return nil, fmt.Errorf("invalid --shadow-price entry %q: price must be positive", item)
+		}
+		points = append(points, pricePoint{strings.TrimSpace(label), price})
+	}
+	return points, nil
+}
+
+// priceScenario is one row of a --shadow-price table.
+type priceScenario struct {
+	pricePoint
+	Result Result
+}
+
+// shadowPrices computes config at each electricity price, in the order
+// given, with every other input fixed.
+func shadowPrices(config Config, points []pricePoint, solar func() (SolarResource, error)) ([]priceScenario, error) {
+	if config.CostHook != "" {
+		return nil, errors.New("--shadow-price sets the electricity cost, which a cost hook replaces; drop --cost-hook")
+	}
+	rows := make([]priceScenario, 0, len(points))
+	for _, point := range points {
+		scenario := config
+		scenario.ElectricityCost = point.Price
+		result, err := computeResult(scenario, solar)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", point.Label, err)
+		}
+		rows = append(rows, priceScenario{point, result})
+	}
+	return rows, nil
+}
+
+// printShadowPrices tabulates the savings at each price against base,
+// the net savings at today's cost.
+func printShadowPrices(w io.Writer, rows []priceScenario, config Config, base float64) {
+	fmt.Fprintf(w, "Savings at projected electricity prices (today $%.3f/kWh, %.2f $/year net):\n\n",
+		config.ElectricityCost, base)
+	fmt.Fprintf(w, "%-10s %10s %14s %14s %10s", "Scenario", "$/kWh", "Savings $/yr", "Net $/yr", "vs today")
+	if config.ProjectCost > 0 {
+		fmt.Fprintf(w, " %10s", "Payback")
+	}
+	fmt.Fprintln(w)
+	for _, r := range rows {
+		change := "-"
+		if base != 0 {
+			change = fmt.Sprintf("%+.1f%%", (r.Result.NetAnnualSavings-base)/base*100)
+		}
+		fmt.Fprintf(w, "%-10s %10.3f %14.2f %14.2f %10s", r.Label, r.Price,
+			r.Result.AnnualCostSaved, r.Result.NetAnnualSavings, change)
+		if config.ProjectCost > 0 {
+			fmt.Fprintf(w, " %10s", paybackText(r.Result.PaybackYears))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// paybackText formats a simple payback in years for a table cell.
+func paybackText(years float64) string {
+	if math.IsInf(years, 1) {
+		return "never"
+	}
+	return fmt.Sprintf("%.1f", years)
+}
+
+// saveShadowPrices writes the price table as CSV to dir, returning the
+// path written.
+func saveShadowPrices(dir string, rows []priceScenario, format outputFormat) (string, error) {
+	file, err := createOutputFile(filepath.Join(dir,
+		"solar_cooling_prices_"+fileTimestamp(format.Now())+".csv"), format.Gzip)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CSV file: %v", err)
+	}
+
+	writer := newCSVWriter(file, format)
+	writer.Write([]string{"Scenario", "Electricity Cost ($/kWh)", "Annual Cost Saved ($)",
+		"Net Annual Savings ($)", "Payback (years)"})
+	for _, r := range rows {
+		payback := ""
+		if r.Result.PaybackYears > 0 {
+			payback = paybackText(r.Result.PaybackYears)
+		}
+		writer.Write([]string{r.Label,
+			strconv.FormatFloat(r.Price, 'g', -1, 64),
+			fmt.Sprintf("%.2f", r.Result.AnnualCostSaved),
+			fmt.Sprintf("%.2f", r.Result.NetAnnualSavings),
+			payback,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.discard()
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.tmp)
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := commitOutputFiles(file); err != nil {
+		return "", err
+	}
+	return file.path, nil
+}
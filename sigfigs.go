@@ -0,0 +1,27 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// roundSigFigs rounds v to n significant figures.
+func roundSigFigs(v float64, n int) float64 {
+	if v == 0 || n <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+	scale := math.Pow(10, float64(n-1)-math.Floor(math.Log10(math.Abs(v))))
+	return math.Round(v*scale) / scale
+}
+
+// formatFigures formats a result for display: to decimals places, or,
+// when sigFigs is positive, to that many significant figures so that
+// 2994.37 at 2 figures reads 3000.
+func formatFigures(v float64, decimals, sigFigs int) string {
+	if sigFigs <= 0 || v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	}
+	rounded := roundSigFigs(v, sigFigs)
+	places := sigFigs - 1 - int(math.Floor(math.Log10(math.Abs(rounded))))
+	return strconv.FormatFloat(rounded, 'f', max(places, 0), 64)
+}
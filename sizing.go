@@ -0,0 +1,27 @@
+package main
+
+// SizingResult is the advisory equipment-capacity reduction implied by
+// the peak cooling load reduction.
+type SizingResult struct {
+	SafetyFactor       float64 `json:"safety_factor"`
+	CapacityReducedKW  float64 `json:"capacity_reduced_kw"`
+	CapacityReducedTon float64 `json:"capacity_reduced_tons"`
+	Advisory           bool    `json:"advisory"`
+}
+
+// equipmentSizing scales the peak cooling reduction by the sizing safety
+// factor designers apply to the design load, giving how much smaller the
+// next cooling unit could be. It is advisory: actual sizing needs a full
+// load calculation such as ACCA Manual N.
+func equipmentSizing(result Result, config Config) *SizingResult {
+	if result.Mode == "heating" || result.PeakCoolingReduced <= 0 {
+		return nil
+	}
+	kw := result.PeakCoolingReduced * config.SizingSafetyFactor
+	return &SizingResult{
+		SafetyFactor:       config.SizingSafetyFactor,
+		CapacityReducedKW:  kw,
+		CapacityReducedTon: kw / kWPerTon,
+		Advisory:           true,
+	}
+}
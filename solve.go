@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// solveInputs are the inputs calc --target-savings can solve for.
+var solveInputs = []string{"shgc", "reduction"}
+
+// designTarget is the value of an input needed for a target saving.
+type designTarget struct {
+	Input    string  // one of solveInputs
+	Target   float64 // annual $ saved
+	Required float64
+	Achieved float64 // annual $ saved at Required, as a check
+}
+
+// Feasible reports whether the required value is physically possible:
+// an SHGC of at most 1. A target that conduction alone meets, with a
+// Required of 0 or less, is feasible at any value.
+func (d designTarget) Feasible() bool {
+	return d.Input != "shgc" || d.Required <= 1
+}
+
+// solveTarget finds the value of input that gives target annual savings
+// with the other inputs fixed. The savings are linear in both the SHGC
+// and the reduction, offset by any conduction savings, so runs of the
+// model at zero and at the current value give the answer, and a third
+// run confirms it.
+func solveTarget(config Config, input string, target float64) (designTarget, error) {
+	d := designTarget{Input: input, Target: target}
+	if config.CostHook != "" {
+		return d, errors.New("--target-savings cannot be used with a cost hook, whose savings need not be proportional")
+	}
+	if len(config.WindowGroups) > 0 || config.SHGCExisting > 0 || config.SHGCProposed > 0 {
+		return d, errors.New("--target-savings needs a single --reduction and --shgc, not window groups or a retrofit")
+	}
+	field := &config.SHGC
+	switch input {
+	case "shgc":
+	case "reduction":
+		field = &config.SolarReduction
+		if *field == 0 {
+			*field = 1
+		}
+	default:
+		return d, fmt.Errorf("cannot solve for %q, expected one of %v", input, solveInputs)
+	}
+
+	current := *field
+	model := modelOf(config)
+	saved := model.Compute(config).AnnualCostSaved
+	*field = 0
+	offset := model.Compute(config).AnnualCostSaved
+	if saved <= offset {
+		return d, fmt.Errorf("no %s gives savings with the other inputs as given", input)
+	}
+	d.Required = current * (target - offset) / (saved - offset)
+	*field = d.Required
+	d.Achieved = model.Compute(config).AnnualCostSaved
+	return d, nil
+}
+
+// printDesignTarget reports the required value and whether it can be
+// reached.
+func printDesignTarget(w io.Writer, d designTarget) {
+	fmt.Fprintf(w, "Target savings: $%.2f/year\n", d.Target)
+	switch {
+	case d.Required <= 0:
+		fmt.Fprintln(w, "Met by the conduction savings alone, at any", d.Input)
+	case d.Input == "shgc" && !d.Feasible():
+		fmt.Fprintf(w, "Infeasible: it needs an SHGC of %.3f, and SHGC cannot exceed 1; "+
+			"a larger --reduction or other inputs must change\n", d.Required)
+	case d.Input == "shgc":
+		fmt.Fprintf(w, "Required SHGC: at least %.3f ($%.2f/year)\n", d.Required, d.Achieved)
+	default:
+		fmt.Fprintf(w, "Required solar reduction: at least %.2f kWh/day ($%.2f/year)\n", d.Required, d.Achieved)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+// systemTypes are the --system-type choices, each with the part-load
+// curve that scales the rated COP at the estimated load fraction.
+var systemTypes = map[string]func(plr float64) float64{
+	// Packaged DX units cycle at part load, losing efficiency linearly
+	// with the AHRI 210/240 default degradation coefficient of 0.15.
+	"dx": func(plr float64) float64 {
+		return 1 - 0.15*(1-plr)
+	},
+	// Centrifugal chillers run no worse, and around half load slightly
+	// better, than at full load before falling off: COP scales by
+	// PLR / EIR-fPLR with the DOE-2 electric centrifugal curve.
+	"chilled-water": func(plr float64) float64 {
+		return plr / (0.222903 + 0.313387*plr + 0.463710*plr*plr)
+	},
+}
+
+// effectiveCOP is the compressor COP at config's load fraction. Both
+// curves are 1 at full load, so the default of DX at a load fraction of
+// 1 is the rated AC_COP.
+func effectiveCOP(config Config) float64 {
+	curve, ok := systemTypes[config.SystemType]
+	if !ok || config.LoadFraction <= 0 {
+		return config.AC_COP
+	}
+	return config.AC_COP * curve(config.LoadFraction)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxAxisPoints bounds each axis of a precomputed table.
+const maxAxisPoints = 1000
+
+// tableAxis is one dimension of a precomputed table: a numeric Config key
+// and the values it takes.
+type tableAxis struct {
+	Key    string
+	Values []float64
+}
+
+// parseTableAxis parses key=start:stop:step, e.g.
+// "solar_reduction=50:500:50", where key is a numeric config file key.
+func parseTableAxis(spec string) (tableAxis, error) {
+	key, rangeSpec, ok := strings.Cut(spec, "=")
+	parts := strings.Split(rangeSpec, ":")
+	if !ok || len(parts) != 3 {
+		return tableAxis{}, fmt.Errorf("invalid table axis %q: expected key=start:stop:step", spec)
+	}
+	key = strings.TrimSpace(key)
+
+	var probe Config
+	field, err := configField(&probe, key)
+	if err != nil {
+		return tableAxis{}, err
+	}
+	if field.Kind() != reflect.Float64 {
+		return tableAxis{}, fmt.Errorf("table axis %s must be a numeric input", key)
+	}
+
+	var bounds [3]float64
+	for i, part := range parts {
+		if bounds[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+			return tableAxis{}, fmt.Errorf("invalid table axis %q: %q is not a number", spec, part)
+		}
+	}
+	start, stop, step := bounds[0], bounds[1], bounds[2]
+	if step <= 0 || stop < start {
+		return tableAxis{}, fmt.Errorf("invalid table axis %q: need start <= stop and a positive step", spec)
+	}
+
+	axis := tableAxis{Key: key}
+	for i := 0; ; i++ {
+		// Index from start rather than accumulating, so float error cannot
+		// drop the last value, and round away the residue (0.1*3).
+		value, _ := strconv.ParseFloat(strconv.FormatFloat(start+float64(i)*step, 'g', 12, 64), 64)
+		if value > stop+step*1e-9 {
+			break
+		}
+		if len(axis.Values) == maxAxisPoints {
+			return tableAxis{}, fmt.Errorf("table axis %s has more than %d values", key, maxAxisPoints)
+		}
+		axis.Values = append(axis.Values, value)
+	}
+	return axis, nil
+}
+
+// precomputeTable computes the annual savings at every combination of
+// the row and column axis values over config. Each cell is validated like
+// a normal run, so an axis reaching an invalid value is an error.
+func precomputeTable(config Config, rows, cols tableAxis, solar func() (SolarResource, error)) ([][]float64, error) {
+	if rows.Key == cols.Key {
+		return nil, fmt.Errorf("table rows and columns must vary different inputs")
+	}
+
+	grid := make([][]float64, len(rows.Values))
+	for i, rowValue := range rows.Values {
+		grid[i] = make([]float64, len(cols.Values))
+		for j, colValue := range cols.Values {
+			scenario := config
+			rowField, _ := configField(&scenario, rows.Key)
+			rowField.SetFloat(rowValue)
+			colField, _ := configField(&scenario, cols.Key)
+			colField.SetFloat(colValue)
+
+			if err := scenario.Validate(); err != nil {
+				return nil, fmt.Errorf("at %s=%g, %s=%g: %s", rows.Key, rowValue, cols.Key, colValue,
+					strings.ReplaceAll(err.Error(), "\n", "; "))
+			}
+			result, err := computeResult(scenario, solar)
+			if err != nil {
+				return nil, err
+			}
+			grid[i][j] = result.AnnualCostSaved
+		}
+	}
+	return grid, nil
+}
+
+// saveTable writes the grid as a CSV matrix to dir: the first row holds
+// the column values, the first column the row values, and each cell the
+// annual savings in $/year. It returns the path written.
+func saveTable(dir string, rows, cols tableAxis, grid [][]float64, format outputFormat) (string, error) {
+	file, err := createOutputFile(filepath.Join(dir,
+		"solar_cooling_table_"+fileTimestamp(format.Now())+".csv"), format.Gzip)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CSV file: %v", err)
+	}
+
+	writer := newCSVWriter(file, format)
+	header := []string{rows.Key + ` \ ` + cols.Key}
+	for _, v := range cols.Values {
+		header = append(header, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	writer.Write(header)
+	for i, v := range rows.Values {
+		record := []string{strconv.FormatFloat(v, 'g', -1, 64)}
+		for _, saved := range grid[i] {
+			record = append(record, fmt.Sprintf("%.2f", saved))
+		}
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.discard()
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.tmp)
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := commitOutputFiles(file); err != nil {
+		return "", err
+	}
+	return file.path, nil
+}
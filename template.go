@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/template"
+)
+
+// templateFuncs are available to --template files besides the
+// text/template builtins: {{fixed 2 .NetAnnualSavings}} formats to two
+// decimals and {{figs 2 .NetAnnualSavings}} to two significant figures.
+var templateFuncs = template.FuncMap{
+	"fixed": func(decimals int, v float64) string {
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	},
+	"figs": func(n int, v float64) string {
+		return formatFigures(v, 0, n)
+	},
+}
+
+// loadTemplate parses the --template file at path. Its data is the
+// result's ResultOutput, the record also written to the JSON file.
+func loadTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(path).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+	return tmpl, nil
+}
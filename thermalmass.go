@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// promptMassRelease is the least share of the gain that is a load within
+// the operating day however heavy the envelope: the convective part,
+// given up by the room air and light furnishings it first warms.
+const promptMassRelease = 0.10
+
+// MassClass is the response of a building envelope of a given thermal
+// mass to the daily solar gain.
+type MassClass struct {
+	LagHours  float64 // delay between the gain and the resulting load
+	Decrement float64 // peak load as a fraction of the undamped peak
+}
+
+// massClasses are typical time lags and decrement factors for light
+// (frame), medium (block) and heavy (concrete) construction, after the
+// wall groups of the ASHRAE cooling load temperature difference method.
+var massClasses = map[string]MassClass{
+	"light":  {LagHours: 2, Decrement: 0.90},
+	"medium": {LagHours: 6, Decrement: 0.65},
+	"heavy":  {LagHours: 10, Decrement: 0.40},
+}
+
+func massClassNames() []string {
+	names := make([]string, 0, len(massClasses))
+	for name := range massClasses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupMassClass(name string) (MassClass, bool) {
+	m, ok := massClasses[strings.ToLower(strings.TrimSpace(name))]
+	return m, ok
+}
+
+// timeLag returns the factors thermal mass applies to the daily load and
+// to the peak, and the lag in hours. Without a --thermal-mass class the
+// scalar TimeLagFactor scales the daily load and the peak follows it.
+//
+// With a class, the gain over the solar window is delayed by the class's
+// lag, and only the share released within the operating day is a load
+// the cooling plant meets; the rest is flushed after hours. The peak is
+// further damped by the decrement factor. At least promptMassRelease of
+// the gain counts, so a lag as long as the day does not zero the load.
+func timeLag(config Config) (total, peak, hours float64) {
+	m, ok := lookupMassClass(config.ThermalMass)
+	if !ok {
+		return config.TimeLagFactor, 1, 0
+	}
+	return math.Max(promptMassRelease, massOverlap(config, m)), m.Decrement, m.LagHours
+}
+
+// massOverlap is the share of the lagged gain released within the
+// operating day, taken to open as the solar window does: the window's
+// gain, shifted by the lag, is cut off at closing time. A round-the-clock
+// building meets all of it.
+func massOverlap(config Config, m MassClass) float64 {
+	if config.OperatingHours >= 24 {
+		return 1
+	}
+	window := peakHours(config)
+	overlap := math.Min(m.LagHours+window, config.OperatingHours) - m.LagHours
+	return math.Max(0, overlap) / window
+}
+
+// massLagWarning explains a thermal mass class whose lag runs the gain
+// past most of the operating day, "" when it does not.
+func massLagWarning(config Config) string {
+	m, ok := lookupMassClass(config.ThermalMass)
+	if !ok || massOverlap(config, m) >= promptMassRelease {
+		return ""
+	}
+	return fmt.Sprintf("%s thermal mass delays the solar gain %g h, so nearly all of it arrives after the %g h operating day; only the %.0f%% released promptly is counted as load",
+		strings.ToLower(strings.TrimSpace(config.ThermalMass)), m.LagHours, config.OperatingHours, 100*promptMassRelease)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTimeLagCountsThePromptReleaseAtLeast(t *testing.T) {
+	tests := []struct {
+		mass  string
+		hours float64
+		total float64
+		warns bool
+	}{
+		{"", 10, 1, false}, // the scalar TimeLagFactor, left at 1
+		{"light", 24, 1, false},
+		{"heavy", 24, 1, false},
+		{"light", 10, 1, false},
+		{"medium", 10, 0.5, false},
+		{"heavy", 14, 0.5, false},
+		{"heavy", 10.5, promptMassRelease, true}, // 0.5 h of 8 overlaps
+		{"heavy", 10, promptMassRelease, true},
+		{"Heavy", 8, promptMassRelease, true},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.ThermalMass, config.OperatingHours, config.TimeLagFactor = tt.mass, tt.hours, 1
+		total, _, _ := timeLag(config)
+		if math.Abs(total-tt.total) > 1e-12 {
+			t.Errorf("%q over %g h: factor = %g, want %g", tt.mass, tt.hours, total, tt.total)
+		}
+		if warning := massLagWarning(config); (warning != "") != tt.warns {
+			t.Errorf("%q over %g h: warning %q, want one: %v", tt.mass, tt.hours, warning, tt.warns)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// tornadoStep is the relative perturbation applied to each input.
+const tornadoStep = 0.10
+
+// tornadoLimits caps inputs whose +10% would leave their valid range.
+var tornadoLimits = map[string]float64{
+	"shgc":            1,
+	"operating_hours": 24,
+	"operating_days":  7,
+}
+
+// SensitivityRow is one bar of a tornado diagram: the annual savings with
+// a single input lowered and raised by tornadoStep.
+type SensitivityRow struct {
+	Input       string
+	Base        float64
+	Low, High   float64 // perturbed input values
+	SavingsLow  float64 // $/year at Low
+	SavingsHigh float64 // $/year at High
+}
+
+// Swing is the spread in annual savings between the two perturbations.
+func (r SensitivityRow) Swing() float64 {
+	if r.SavingsHigh > r.SavingsLow {
+		return r.SavingsHigh - r.SavingsLow
+	}
+	return r.SavingsLow - r.SavingsHigh
+}
+
+// tornado perturbs each calculation input independently and returns the
+// rows sorted by swing, largest first, along with the base savings.
+func tornado(config Config, solar func() (SolarResource, error)) ([]SensitivityRow, float64, error) {
+	base, err := computeResult(config, solar)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	savingsWith := func(key string, value float64) (float64, error) {
+		scenario := config
+		field, err := configField(&scenario, key)
+		if err != nil {
+			return 0, err
+		}
+		field.SetFloat(value)
+		result, err := computeResult(scenario, solar)
+		return result.AnnualCostSaved, err
+	}
+
+	rows := make([]SensitivityRow, 0, len(confidenceInputs))
+	for _, key := range confidenceInputs {
+		field, err := configField(&config, key)
+		if err != nil {
+			return nil, 0, err
+		}
+		row := SensitivityRow{Input: key, Base: field.Float()}
+		row.Low = row.Base * (1 - tornadoStep)
+		row.High = row.Base * (1 + tornadoStep)
+		if limit, ok := tornadoLimits[key]; ok && row.High > limit {
+			row.High = limit
+		}
+		if row.SavingsLow, err = savingsWith(key, row.Low); err != nil {
+			return nil, 0, err
+		}
+		if row.SavingsHigh, err = savingsWith(key, row.High); err != nil {
+			return nil, 0, err
+		}
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Swing() > rows[j].Swing() })
+	return rows, base.AnnualCostSaved, nil
+}
+
+// printTornado writes the sensitivity rows as a table.
+func printTornado(w io.Writer, rows []SensitivityRow, base float64) {
+	fmt.Fprintf(w, "Sensitivity of annual cost savings (%.2f $/year) to ±%.0f%% in each input:\n\n",
+		base, tornadoStep*100)
+	fmt.Fprintf(w, "%-22s %10s %10s %10s %12s %12s %10s\n",
+		"Input", "Base", "Low", "High", "$/yr at low", "$/yr at high", "Swing")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%-22s %10.3f %10.3f %10.3f %12.2f %12.2f %10.2f\n",
+			r.Input, r.Base, r.Low, r.High, r.SavingsLow, r.SavingsHigh, r.Swing())
+	}
+}
+
+// saveTornado writes the sensitivity rows as CSV to dir, returning the
+// path written.
+func saveTornado(dir string, rows []SensitivityRow, format outputFormat) (string, error) {
+	file, err := createOutputFile(filepath.Join(dir,
+		"solar_cooling_tornado_"+fileTimestamp(format.Now())+".csv"), format.Gzip)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CSV file: %v", err)
+	}
+
+	writer := newCSVWriter(file, format)
+	writer.Write([]string{"Input", "Base Value", "Low Value", "High Value",
+		"Savings Low ($/year)", "Savings High ($/year)", "Swing ($/year)"})
+	for _, r := range rows {
+		writer.Write([]string{r.Input,
+			strconv.FormatFloat(r.Base, 'g', -1, 64),
+			strconv.FormatFloat(r.Low, 'g', -1, 64),
+			strconv.FormatFloat(r.High, 'g', -1, 64),
+			fmt.Sprintf("%.2f", r.SavingsLow),
+			fmt.Sprintf("%.2f", r.SavingsHigh),
+			fmt.Sprintf("%.2f", r.Swing()),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.discard()
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.tmp)
+		return "", fmt.Errorf("failed to write CSV file: %v", err)
+	}
+	if err := commitOutputFiles(file); err != nil {
+		return "", err
+	}
+	return file.path, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// flagUnit is the unit a float flag is given in and the suffixes, lower
+// case and without spaces, that may spell it after the number.
+type flagUnit struct {
+	Name     string
+	Suffixes []string
+}
+
+var (
+	unitKWhPerDay = flagUnit{"kWh/day", []string{"kwh", "kwh/day", "kwh/d"}}
+	unitUSDPerKWh = flagUnit{"$/kWh", []string{"usd", "$", "usd/kwh", "$/kwh"}}
+	unitUSD       = flagUnit{"$", []string{"usd", "$"}}
+	unitUSDPerT   = flagUnit{"$/tonne", []string{"usd/t", "$/t", "usd/tonne", "$/tonne"}}
+	unitM2        = flagUnit{"m²", []string{"m2", "m²", "sqm"}}
+	unitUFactor   = flagUnit{"W/m²K", []string{"w/m2k", "w/m²k", "w/m2/k", "w/m²/k"}}
+	unitKelvin    = flagUnit{"K", []string{"k"}}
+	unitKgPerKWh  = flagUnit{"kg CO2e/kWh", []string{"kg/kwh", "kgco2/kwh", "kgco2e/kwh"}}
+	unitKgPerYear = flagUnit{"kg CO2e/year", []string{"kg", "kg/year", "kg/yr", "kgco2e", "kgco2e/year"}}
+)
+
+// otherUnits are suffixes no flag takes but users plausibly type, so that
+// --reduction 150W reports the wrong unit rather than an unknown one.
+var otherUnits = []string{
+	"w", "kw", "mw", "wh", "mwh", "btu", "kbtu", "therm", "ton", "tons",
+	"ft2", "ft²", "sqft", "c", "°c", "f", "°f", "t", "tonne", "lb",
+	"w/m2", "w/m²", "kwh/m2", "kwh/m²", "kwh/year", "kwh/yr",
+}
+
+// knownUnit reports whether suffix spells any unit a flag takes or
+// otherUnits lists.
+func knownUnit(suffix string) bool {
+	for _, unit := range []flagUnit{unitKWhPerDay, unitUSDPerKWh, unitUSD, unitUSDPerT,
+		unitM2, unitUFactor, unitKelvin, unitKgPerKWh, unitKgPerYear} {
+		if slices.Contains(unit.Suffixes, suffix) {
+			return true
+		}
+	}
+	return slices.Contains(otherUnits, suffix)
+}
+
+// unitFloat is a float64 flag that also accepts its unit after the
+// number, as in --reduction 100kWh or --cost $0.15. The unit is only
+// checked and stripped, never converted, so another unit is an error.
+type unitFloat struct {
+	value *float64
+	unit  flagUnit
+}
+
+// unitFloatVarP defines a unitFloat flag like pflag's Float64VarP.
+func unitFloatVarP(fs *pflag.FlagSet, p *float64, name, shorthand string, value float64, unit flagUnit, usage string) {
+	*p = value
+	fs.VarP(&unitFloat{value: p, unit: unit}, name, shorthand, usage)
+}
+
+// unitFloatVar defines a unitFloat flag without a shorthand.
+func unitFloatVar(fs *pflag.FlagSet, p *float64, name string, value float64, unit flagUnit, usage string) {
+	unitFloatVarP(fs, p, name, "", value, unit, usage)
+}
+
+func (f *unitFloat) Set(text string) error {
+	number := strings.TrimSpace(text)
+	if rest, ok := strings.CutPrefix(number, "$"); ok && f.accepts("$") {
+		number = strings.TrimSpace(rest)
+	}
+	end := strings.IndexFunc(number, func(r rune) bool {
+		return !strings.ContainsRune("0123456789.+-eE", r)
+	})
+	if end < 0 {
+		end = len(number)
+	}
+	if suffix := strings.ToLower(strings.Join(strings.Fields(number[end:]), "")); suffix != "" {
+		switch {
+		case f.accepts(suffix):
+		case knownUnit(suffix):
+			return fmt.Errorf("unit %s does not match %s", strings.TrimSpace(number[end:]), f.unit.Name)
+		default:
+			return fmt.Errorf("unknown unit %q, expected %s", strings.TrimSpace(number[end:]), f.unit.Name)
+		}
+	}
+	value, err := strconv.ParseFloat(number[:end], 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", text)
+	}
+	*f.value = value
+	return nil
+}
+
+// accepts reports whether suffix spells the flag's unit.
+func (f *unitFloat) accepts(suffix string) bool {
+	return slices.Contains(f.unit.Suffixes, suffix)
+}
+
+func (f *unitFloat) String() string {
+	if f.value == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*f.value, 'g', -1, 64)
+}
+
+// Type is float64, so usage and shell completion treat it as pflag's own.
+func (f *unitFloat) Type() string {
+	return "float64"
+}
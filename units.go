@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// dimensionless is the unit of ratios, factors and fractions in
+// --json-with-units output.
+const dimensionless = "1"
+
+// outputUnits maps every numeric JSON key of a result, at any depth, to
+// its unit. The energy, power and money units come from units, so that
+// heating results label their energy as heating energy.
+func outputUnits(units Units) map[string]string {
+	return map[string]string{
+		// inputs
+		"solar_reduction_kwh_day":  units.SolarRadiation,
+		"electricity_cost_per_kwh": units.Cost,
+		"ac_cop":                   dimensionless,
+		"effective_cop":            dimensionless,
+		"shgc":                     dimensionless,
+		"wwr":                      dimensionless,
+		"transmission_factor":      dimensionless,
+		"time_lag_factor":          dimensionless,
+		"medical_equip_factor":     dimensionless,
+		"operating_hours_per_day":  "h/day",
+		"operating_days_per_week":  "days/week",
+		"pv_offset_fraction":       dimensionless,
+		"shgc_existing":            dimensionless,
+		"shgc_proposed":            dimensionless,
+
+		// results
+		"cooling_load_reduced_kwh_day":         units.CoolingLoad,
+		"solar_load_reduced_kwh_day":           units.CoolingLoad,
+		"conduction_load_reduced_kwh_day":      units.CoolingLoad,
+		"electricity_saved_kwh_day":            units.Electricity,
+		"compressor_electricity_saved_kwh_day": units.Electricity,
+		"grid_electricity_saved_kwh_day":       units.Electricity,
+		"self_consumption_saved_kwh_day":       units.Electricity,
+		"operating_factor":                     dimensionless,
+		"operating_days_per_year":              "days/year",
+		"time_lag_hours":                       "h",
+		"peak_cooling_reduced_kw":              units.PeakCooling,
+		"peak_electricity_reduced_kw":          units.PeakElectricity,
+		"peak_tons_reduced":                    "tons",
+		"annual_cost_saved_usd":                units.Savings, // also the roof's
+		"shaped_rate_usd_per_kwh":              units.Cost,
+		"coincident_demand_reduced_kw":         units.PeakElectricity,
+		"dr_incentive_usd_year":                units.Savings,
+		"heating_penalty_usd_year":             units.Savings,
+		"net_annual_savings_usd":               units.Savings,
+		"percent_of_bill":                      "%",
+		"payback_years":                        "years",
+		"grid_co2_kg_per_kwh":                  "kg CO2e/kWh",
+		"co2_avoided_kg_day":                   "kg CO2e/day",
+		"co2_avoided_kg_year":                  "kg CO2e/year",
+		"percent_emissions_reduced":            "%",
+		"carbon_value_usd_year":                units.Savings,
+		"total_annual_benefit_usd":             units.Savings,
+		"days_delayed":                         "days",
+		"foregone_savings_usd":                 "$",
+
+		// roof, equipment_sizing, code_baseline, baseline, window_groups
+		"area_m2":                           "m²",
+		"area":                              "m²",
+		"absorptance":                       dimensionless,
+		"proposed_absorptance":              dimensionless,
+		"u_factor_w_m2k":                    "W/m²K",
+		"irradiance_kwh_m2_day":             "kWh/m²/day",
+		"safety_factor":                     dimensionless,
+		"capacity_reduced_kw":               units.PeakCooling,
+		"capacity_reduced_tons":             "tons",
+		"code_annual_cost_saved_usd":        units.Savings,
+		"incremental_annual_cost_saved_usd": units.Savings,
+		"incremental_co2_avoided_kg_year":   "kg CO2e/year",
+		"baseline_net_annual_savings_usd":   units.Savings,
+		"net_annual_savings_delta_usd":      units.Savings,
+		"electricity_saved_delta_kwh_day":   units.Electricity,
+		"peak_electricity_reduced_delta_kw": units.PeakElectricity,
+		"co2_avoided_delta_kg_year":         "kg CO2e/year",
+
+		// savings_intensity
+		"glazed_area_m2":                            "m²",
+		"floor_area_m2":                             "m²",
+		"savings_usd_per_m2_glazing_year":           "$/m²/year",
+		"electricity_saved_kwh_per_m2_glazing_year": "kWh/m²/year",
+		"savings_usd_per_m2_floor_year":             "$/m²/year",
+		"electricity_saved_kwh_per_m2_floor_year":   "kWh/m²/year",
+
+		// monthly_savings
+		"cdd":                   "degree-days",
+		"electricity_saved_kwh": "kWh",
+		"cost_saved_usd":        "$",
+	}
+}
+
+// withUnits returns output as JSON with each numeric value that has a
+// unit replaced by a Quantity, keeping the field order. The intermediates
+// are Quantities already.
+func withUnits(output ResultOutput) (json.RawMessage, error) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := readJSONNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	annotateUnits(node, outputUnits(output.Units))
+	return json.Marshal(node)
+}
+
+// jsonMember is one key and value of a jsonObject.
+type jsonMember struct {
+	Key   string
+	Value any
+}
+
+// jsonObject is a JSON object that keeps its keys in the order read,
+// unlike a map.
+type jsonObject []jsonMember
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(member.Key)
+		value, err := json.Marshal(member.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// readJSONNode reads one value from decoder, objects as jsonObject,
+// arrays as []any and numbers as json.Number.
+func readJSONNode(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		var object jsonObject
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, jsonMember{fmt.Sprint(key), value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []any{}
+		for decoder.More() {
+			value, err := readJSONNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+// annotateUnits replaces the numbers under keys with a unit throughout
+// node.
+func annotateUnits(node any, units map[string]string) {
+	switch n := node.(type) {
+	case jsonObject:
+		for i, member := range n {
+			if member.Key == "intermediates" {
+				continue
+			}
+			if number, ok := member.Value.(json.Number); ok {
+				if unit, ok := units[member.Key]; ok {
+					value, _ := number.Float64()
+					n[i].Value = Quantity{Value: value, Units: unit}
+				}
+				continue
+			}
+			annotateUnits(member.Value, units)
+		}
+	case []any:
+		for _, item := range n {
+			annotateUnits(item, units)
+		}
+	}
+}
+
+// resultsWithUnits applies withUnits to the result files' JSON value, a
+// ResultOutput or a slice of them; other values are returned unchanged.
+func resultsWithUnits(v any) (any, error) {
+	switch v := v.(type) {
+	case ResultOutput:
+		return withUnits(v)
+	case []ResultOutput:
+		annotated := make([]json.RawMessage, len(v))
+		for i, output := range v {
+			var err error
+			if annotated[i], err = withUnits(output); err != nil {
+				return nil, err
+			}
+		}
+		return annotated, nil
+	}
+	return v, nil
+}
+
+// withoutUnits reverses withUnits on result JSON, replacing each Quantity
+// outside the intermediates with its value, so files written with
+// --json-with-units decode into ResultOutput. Plain result JSON comes back
+// as it was, bar whitespace.
+func withoutUnits(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := readJSONNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	stripUnits(node)
+	return json.Marshal(node)
+}
+
+// stripUnits replaces the {value, units} objects throughout node with
+// their value.
+func stripUnits(node any) {
+	switch n := node.(type) {
+	case jsonObject:
+		for i, member := range n {
+			if member.Key == "intermediates" {
+				continue
+			}
+			if value, ok := quantityValue(member.Value); ok {
+				n[i].Value = value
+				continue
+			}
+			stripUnits(member.Value)
+		}
+	case []any:
+		for _, item := range n {
+			stripUnits(item)
+		}
+	}
+}
+
+// quantityValue returns the number in node when it is a Quantity object
+// as annotateUnits writes it.
+func quantityValue(node any) (json.Number, bool) {
+	object, ok := node.(jsonObject)
+	if !ok || len(object) != 2 || object[0].Key != "value" || object[1].Key != "units" {
+		return "", false
+	}
+	value, ok := object[0].Value.(json.Number)
+	if _, unit := object[1].Value.(string); !unit {
+		return "", false
+	}
+	return value, ok
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// runValidate is the validate command: check the inputs the way calc
+// would, print OK or every problem found, and exit.
+func runValidate(args []string) int {
+	config := DefaultConfig()
+	var failOnWarn bool
+
+	fs := pflag.NewFlagSet("validate", pflag.ExitOnError)
+	flags := bindConfigFlags(fs, &config)
+	fs.BoolVar(&failOnWarn, "fail-on-warning", false,
+		"Exit non-zero if any warning was printed")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  calculator validate [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Checks the configuration from --config and flags, printing OK or the errors.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	flags.record()
+	fileKeys, err := flags.load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return failOnWarnings(failOnWarn, reportValidation(config, flags.sources(fileKeys)))
+}
+
+// reportValidation estimates any missing inputs and validates config,
+// printing OK or each error, and returns the exit code.
+func reportValidation(config Config, sources map[string]string) int {
+	solar := newSolarLookup(&config, warnings, io.Discard)
+	err := estimateInputs(&config, sources, solar, io.Discard)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err == nil && config.Strict {
+		if missing := unaffirmedAssumptions(config, sources); len(missing) > 0 {
+			err = fmt.Errorf("--strict requires explicit values for: --%s", strings.Join(missing, ", --"))
+		}
+	}
+	if err != nil {
+		printErrors(err)
+		return 1
+	}
+	printWarnings(warnings, config)
+	fmt.Println("OK")
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// warningLog writes warnings through to out and counts them, so that
+// --fail-on-warning can fail a run once every warning has been printed.
+// Each Write is one warning. serve handlers write concurrently, so the
+// count and the writes are serialized.
+type warningLog struct {
+	mu    sync.Mutex
+	out   io.Writer
+	count int
+	style ansiStyle
+}
+
+func (l *warningLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.count++
+	if !l.style.on {
+		return l.out.Write(p)
+	}
+	text := strings.TrimSuffix(string(p), "\n")
+	if _, err := io.WriteString(l.out, l.style.warning(text)+string(p[len(text):])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// warnings collects every warning the commands print.
+var warnings io.Writer = &warningLog{out: os.Stderr}
+
+// warningCount is the number of warnings printed so far.
+func warningCount() int {
+	if l, ok := warnings.(*warningLog); ok {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.count
+	}
+	return 0
+}
+
+// failOnWarnings turns a successful status into a failure when
+// --fail-on-warning is set and any warning was printed.
+func failOnWarnings(enabled bool, status int) int {
+	if !enabled || status != 0 || warningCount() == 0 {
+		return status
+	}
+	fmt.Printf("Error: %d warning(s) printed and --fail-on-warning is set\n", warningCount())
+	return 1
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce collapses the burst of events editors emit for one save.
+const watchDebounce = 200 * time.Millisecond
+
+// watchFiles calls onChange once immediately and again after each change
+// to any of paths, until ctx is cancelled. The parent directories are
+// watched rather than the files themselves so that editors which save by
+// renaming a temporary file over the original are still picked up.
+func watchFiles(ctx context.Context, paths []string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	targets := make(map[string]bool, len(paths))
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		target, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %v", path, err)
+		}
+		targets[target] = true
+		if dir := filepath.Dir(target); !dirs[dir] {
+			if err := watcher.Add(dir); err != nil {
+				return fmt.Errorf("failed to watch %s: %v", path, err)
+			}
+			dirs[dir] = true
+		}
+	}
+
+	onChange()
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !targets[filepath.Clean(event.Name)] {
+				continue
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("file watcher failed: %v", err)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
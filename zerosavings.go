@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// nearZeroSavings is the annual savings in $ below which the result is
+// explained rather than just printed as $0.00.
+const nearZeroSavings = 1.0
+
+// savingsFactor is one link of the multiplicative chain from solar
+// reduction to annual savings, with a typical value to compare it to.
+type savingsFactor struct {
+	Name    string
+	Flag    string
+	Value   float64
+	Typical float64
+	Inverse bool // savings fall as the value rises, as for the COP
+}
+
+// relative is how much the factor scales savings compared with its
+// typical value; below 1 it is holding savings down.
+func (f savingsFactor) relative() float64 {
+	if f.Inverse {
+		return f.Typical / f.Value
+	}
+	return f.Value / f.Typical
+}
+
+// savingsFactors lists the chain behind result. The typical values are
+// the defaults, or for the required inputs a mid-size clinic.
+func savingsFactors(result Result, config Config) []savingsFactor {
+	a := result.Assumptions
+	factors := []savingsFactor{
+		{"solar reduction", "reduction", math.Abs(result.TotalSolarReduction), 100, false},
+		{"SHGC", "shgc", a.SHGC, 0.25, false},
+		{"transmission factor", "transmission-factor", a.TransmissionFactor, 0.80, false},
+		{"time lag factor", "time-lag-factor", a.TimeLagFactor, 0.95, false},
+		{"COP", "cop", a.AC_COP, 4.0, true},
+		{"operating schedule factor", "operating-hours", result.OperatingFactor, 1, false},
+		{"grid share of electricity", "pv-offset-fraction", gridFraction(config), 1, false},
+	}
+	if result.Mode == "heating" {
+		factors[4] = savingsFactor{"heating COP", "heating-cop", a.AC_COP, 3.0, true}
+	}
+	if config.ThermalMass != "" {
+		factors[3].Name, factors[3].Flag, factors[3].Typical = "share of lagged gain in operating hours", "thermal-mass", 1
+	}
+	if config.CostHook == "" {
+		factors = append(factors, savingsFactor{"electricity cost", "cost", a.ElectricityCost, 0.15, false})
+	}
+	return factors
+}
+
+// zeroSavingsNote explains a near-zero result by the factor furthest
+// below its typical value, or returns "" when savings are not near zero.
+func zeroSavingsNote(result Result, config Config) string {
+	if math.Abs(result.AnnualCostSaved) >= nearZeroSavings {
+		return ""
+	}
+	factors := savingsFactors(result, config)
+	limit := factors[0]
+	for _, f := range factors[1:] {
+		if f.relative() < limit.relative() {
+			limit = f
+		}
+	}
+	if config.CostHook != "" && limit.relative() >= 0.5 {
+		return fmt.Sprintf("Savings are under $%.0f/year because the cost hook priced %.2f kWh/day at $%.2f/year",
+			nearZeroSavings, result.ElectricitySaved, result.AnnualCostSaved)
+	}
+	return fmt.Sprintf("Savings are under $%.0f/year, limited mainly by the %s of %.3g (--%s; typically %g)",
+		nearZeroSavings, limit.Name, limit.Value, limit.Flag, limit.Typical)
+}